@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/ii/xds-test-harness/internal/report"
+	"github.com/ii/xds-test-harness/internal/types"
+	"github.com/spf13/pflag"
+)
+
+// The diff subcommand compares two results files, like those from two nightly runs:
+//
+//	xds-test-harness diff [--json] old-results.json new-results.json
+//
+// It exits with exitFailed when the later run has regressions.
+func diffCommand(args []string) int {
+	flags := pflag.NewFlagSet("diff", pflag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the diff as json instead of text")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: xds-test-harness diff [--json] BEFORE AFTER")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return exitSetupError
+	}
+
+	before, err := readResults(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitSetupError
+	}
+	after, err := readResults(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitSetupError
+	}
+
+	diff := report.Diff(before, after)
+	if *asJSON {
+		out, _ := json.MarshalIndent(diff, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		fmt.Print(diff.Text())
+	}
+	if diff.Regressed() {
+		return exitFailed
+	}
+	return exitPassed
+}
+
+func readResults(path string) (results types.Results, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return results, fmt.Errorf("cannot read results: %v", err)
+	}
+	if err := json.Unmarshal(data, &results); err != nil {
+		return results, fmt.Errorf("cannot parse results from %v: %v", path, err)
+	}
+	return results, nil
+}
@@ -8,6 +8,7 @@ import (
 	"math/rand"
 	"net"
 	"os"
+	"sync"
 	"time"
 
 	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
@@ -29,6 +30,8 @@ import (
 )
 
 const (
+	restartTimeout = 10 * time.Second
+
 	TypeUrlLDS = "type.googleapis.com/envoy.config.listener.v3.Listener"
 	TypeUrlCDS = "type.googleapis.com/envoy.config.cluster.v3.Cluster"
 	TypeUrlRDS = "type.googleapis.com/envoy.config.route.v3.RouteConfiguration"
@@ -36,7 +39,14 @@ const (
 )
 
 var (
-	xdsCache      cache.SnapshotCache
+	// main swaps the cache when it restarts the management server, while
+	// adapter calls are using it.
+	cacheMu  sync.RWMutex
+	xdsCache cache.SnapshotCache
+	// The cache keys of every node ID the adapter set state for, one per
+	// identity a client on it was given.
+	keysMu        sync.Mutex
+	nodeKeys      = map[string]map[string]bool{}
 	resourceTypes = map[string]types.ResponseType{
 		TypeUrlCDS: types.Cluster,
 		TypeUrlLDS: types.Listener,
@@ -224,6 +234,59 @@ func MakeRuntime(runtimeName string) *runtime.Runtime {
 	}
 }
 
+// Keys the cache by the node's cluster, locality and metadata along with its
+// ID, so clients sharing a node ID can be served different resources. A node
+// with none of these is keyed by its ID alone, as cache.IDHash does.
+type IdentityHash struct{}
+
+func (IdentityHash) ID(node *core.Node) string {
+	if node == nil {
+		return ""
+	}
+	if node.Cluster == "" && node.Locality == nil && len(node.GetMetadata().GetFields()) == 0 {
+		return node.Id
+	}
+	metadata, _ := json.Marshal(node.GetMetadata().AsMap())
+	locality := node.GetLocality()
+	return fmt.Sprintf("%v/%v/%v/%v/%v/%s", node.Id, node.Cluster, locality.GetRegion(), locality.GetZone(), locality.GetSubZone(), metadata)
+}
+
+// The cache key for the node the state is for, which is remembered so changes
+// to the node ID can reach every identity on it.
+func stateKey(request *pb.SetStateRequest) (string, error) {
+	node := &core.Node{}
+	if request.NodeIdentity != nil {
+		if err := request.NodeIdentity.UnmarshalTo(node); err != nil {
+			return "", fmt.Errorf("cannot read node identity: %v", err)
+		}
+	}
+	node.Id = request.Node
+	key := IdentityHash{}.ID(node)
+
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	if nodeKeys[request.Node] == nil {
+		nodeKeys[request.Node] = make(map[string]bool)
+	}
+	nodeKeys[request.Node][key] = true
+	return key, nil
+}
+
+// The cache keys for the node ID, which is its own key when it was never
+// given an identity.
+func keysFor(node string) []string {
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	if len(nodeKeys[node]) == 0 {
+		return []string{node}
+	}
+	keys := []string{}
+	for key := range nodeKeys[node] {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
 func (a *adapterServer) SetState(ctx context.Context, request *pb.SetStateRequest) (response *pb.SetStateResponse, err error) {
 	snapshot, err := cache.NewSnapshot("1", make(map[string][]types.Resource))
 	if err != nil {
@@ -235,34 +298,40 @@ func (a *adapterServer) SetState(ctx context.Context, request *pb.SetStateReques
 	routes := []types.Resource{}
 
 	for _, resourceReq := range request.Resources {
+		version := versionForType(request, resourceReq.TypeUrl)
 		switch resourceReq.TypeUrl {
 		case TypeUrlCDS:
 			var c cluster.Cluster
 			err = resourceReq.UnmarshalTo(&c)
 			clusters = append(clusters, MakeCluster(c.Name, request.Node))
-			snapshot.Resources[types.Cluster] = cache.NewResources(request.Version, clusters)
+			snapshot.Resources[types.Cluster] = cache.NewResources(version, clusters)
 		case TypeUrlLDS:
 			var r listener.Listener
 			err = resourceReq.UnmarshalTo(&r)
 			listeners = append(listeners, makeListener(r.Name, randomAddress(), 10000, []*listener.FilterChain{}))
-			snapshot.Resources[types.Listener] = cache.NewResources(request.Version, listeners)
+			snapshot.Resources[types.Listener] = cache.NewResources(version, listeners)
 		case TypeUrlEDS:
 			var r endpoint.ClusterLoadAssignment
 			err = resourceReq.UnmarshalTo(&r)
 			endpoints = append(endpoints, MakeEndpoint(r.ClusterName, randomAddress(), 10000))
-			snapshot.Resources[types.Endpoint] = cache.NewResources(request.Version, endpoints)
+			snapshot.Resources[types.Endpoint] = cache.NewResources(version, endpoints)
 		case TypeUrlRDS:
 			var r route.RouteConfiguration
 			err = resourceReq.UnmarshalTo(&r)
 			routes = append(routes, MakeRoute(r.Name, r.Name))
-			snapshot.Resources[types.Route] = cache.NewResources(request.Version, routes)
+			snapshot.Resources[types.Route] = cache.NewResources(version, routes)
 		}
 	}
-	if err := xdsCache.SetSnapshot(context.Background(), request.Node, snapshot); err != nil {
+	key, err := stateKey(request)
+	if err != nil {
+		return nil, err
+	}
+	setResourceVersions(key, request.ResourceVersions)
+	if err := currentCache().SetSnapshot(context.Background(), key, snapshot); err != nil {
 		log.Printf("snapshot error %q for %+v", err, snapshot)
 		os.Exit(1)
 	}
-	newSnapshot, _ := xdsCache.GetSnapshot(request.Node)
+	newSnapshot, _ := currentCache().GetSnapshot(key)
 	prettySnap, _ := json.Marshal(newSnapshot)
 	fmt.Printf("new snapshot: \n%v\n\n", string(prettySnap))
 
@@ -272,9 +341,24 @@ func (a *adapterServer) SetState(ctx context.Context, request *pb.SetStateReques
 	return response, nil
 }
 
+// The version for all resources of the given type, falling back to the
+// request's version when the type has none of its own.
+func versionForType(request *pb.SetStateRequest, typeUrl string) string {
+	if version, ok := request.TypeVersions[typeUrl]; ok {
+		return version
+	}
+	return request.Version
+}
+
 func (a *adapterServer) ClearState(ctx context.Context, req *pb.ClearStateRequest) (*pb.ClearStateResponse, error) {
 	log.Printf("Clearing Cache")
-	xdsCache.ClearSnapshot(req.Node)
+	for _, key := range keysFor(req.Node) {
+		currentCache().ClearSnapshot(key)
+		setResourceVersions(key, nil)
+	}
+	keysMu.Lock()
+	delete(nodeKeys, req.Node)
+	keysMu.Unlock()
 	response := &pb.ClearStateResponse{
 		Response: "All Clear",
 	}
@@ -316,13 +400,25 @@ func updateForType(res types.Resource) (uppedRes types.Resource) {
 // Set a new snapshot to the cache with everything the same as the current state, except for the requested resource updated
 // in some meaningful way.  The server will see this update and notify the client.
 func (a *adapterServer) UpdateResource(ctx context.Context, request *pb.ResourceRequest) (*pb.UpdateResourceResponse, error) {
+	for _, key := range keysFor(request.Node) {
+		if err := updateResource(key, request); err != nil {
+			return nil, err
+		}
+	}
+	response := &pb.UpdateResourceResponse{
+		Success: true,
+	}
+	return response, nil
+}
+
+func updateResource(key string, request *pb.ResourceRequest) error {
 	snapshot, err := cache.NewSnapshot("1", make(map[string][]types.Resource))
 	if err != nil {
-		return nil, err
+		return err
 	}
-	state, err := xdsCache.GetSnapshot(request.Node)
+	state, err := currentCache().GetSnapshot(key)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// The function to return the current snapshot returns a
@@ -348,26 +444,36 @@ func (a *adapterServer) UpdateResource(ctx context.Context, request *pb.Resource
 		// it to the given version with a notification to the client. So though we pass in a new version for all,
 		// it should only really apply when a resource has actually changed.
 	}
-	if err := xdsCache.SetSnapshot(context.Background(), request.Node, snapshot); err != nil {
-		return nil, err
+	if err := currentCache().SetSnapshot(context.Background(), key, snapshot); err != nil {
+		return err
 	}
-	newSnapshot, _ := xdsCache.GetSnapshot(request.Node)
+	forgetResourceVersion(key, request.TypeUrl, request.ResourceName)
+	newSnapshot, _ := currentCache().GetSnapshot(key)
 	prettySnap, _ := json.Marshal(newSnapshot)
 	fmt.Printf("new snapshot after update: \n%v\n\n", string(prettySnap))
-	response := &pb.UpdateResourceResponse{
+	return nil
+}
+
+func (a *adapterServer) AddResource(ctx context.Context, request *pb.ResourceRequest) (*pb.AddResourceResponse, error) {
+	for _, key := range keysFor(request.Node) {
+		if err := addResource(key, request); err != nil {
+			return nil, err
+		}
+	}
+	response := &pb.AddResourceResponse{
 		Success: true,
 	}
 	return response, nil
 }
 
-func (a *adapterServer) AddResource(ctx context.Context, request *pb.ResourceRequest) (*pb.AddResourceResponse, error) {
+func addResource(key string, request *pb.ResourceRequest) error {
 	snapshot, err := cache.NewSnapshot("1", make(map[string][]types.Resource))
 	if err != nil {
-		return nil, err
+		return err
 	}
-	state, err := xdsCache.GetSnapshot(request.Node)
+	state, err := currentCache().GetSnapshot(key)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	for typeUrl, resType := range resourceTypes {
@@ -381,26 +487,35 @@ func (a *adapterServer) AddResource(ctx context.Context, request *pb.ResourceReq
 		}
 		snapshot.Resources[resType] = cache.NewResources(request.Version, resources)
 	}
-	if err := xdsCache.SetSnapshot(context.Background(), request.Node, snapshot); err != nil {
-		return nil, err
+	if err := currentCache().SetSnapshot(context.Background(), key, snapshot); err != nil {
+		return err
 	}
-	newSnapshot, _ := xdsCache.GetSnapshot(request.Node)
+	newSnapshot, _ := currentCache().GetSnapshot(key)
 	prettySnap, _ := json.Marshal(newSnapshot)
 	fmt.Printf("new snapshot after addition: \n%v\n\n", string(prettySnap))
-	response := &pb.AddResourceResponse{
+	return nil
+}
+
+func (a *adapterServer) RemoveResource(ctx context.Context, request *pb.ResourceRequest) (*pb.RemoveResourceResponse, error) {
+	for _, key := range keysFor(request.Node) {
+		if err := removeResource(key, request); err != nil {
+			return nil, err
+		}
+	}
+	response := &pb.RemoveResourceResponse{
 		Success: true,
 	}
 	return response, nil
 }
 
-func (a *adapterServer) RemoveResource(ctx context.Context, request *pb.ResourceRequest) (*pb.RemoveResourceResponse, error) {
+func removeResource(key string, request *pb.ResourceRequest) error {
 	snapshot, err := cache.NewSnapshot("1", make(map[string][]types.Resource))
 	if err != nil {
-		return nil, err
+		return err
 	}
-	state, err := xdsCache.GetSnapshot(request.Node)
+	state, err := currentCache().GetSnapshot(key)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	for typeUrl, resType := range resourceTypes {
@@ -415,20 +530,49 @@ func (a *adapterServer) RemoveResource(ctx context.Context, request *pb.Resource
 		}
 		snapshot.Resources[resType] = cache.NewResources(request.Version, resources)
 	}
-	if err := xdsCache.SetSnapshot(context.Background(), request.Node, snapshot); err != nil {
-		return nil, err
+	if err := currentCache().SetSnapshot(context.Background(), key, snapshot); err != nil {
+		return err
 	}
-	newSnapshot, _ := xdsCache.GetSnapshot(request.Node)
+	forgetResourceVersion(key, request.TypeUrl, request.ResourceName)
+	newSnapshot, _ := currentCache().GetSnapshot(key)
 	prettySnap, _ := json.Marshal(newSnapshot)
 	fmt.Printf("new snapshot after removal: \n%v\n\n", string(prettySnap))
-	response := &pb.RemoveResourceResponse{
+	return nil
+}
+
+// Stops the management server, which main starts again with an empty cache,
+// so the target comes back the way a real control plane would after a restart.
+func (a *adapterServer) Restart(ctx context.Context, request *pb.RestartRequest) (*pb.RestartResponse, error) {
+	log.Printf("Restarting management server")
+	if err := restartServer(restartTimeout); err != nil {
+		return nil, err
+	}
+	response := &pb.RestartResponse{
 		Success: true,
 	}
 	return response, nil
 }
 
-func RunAdapter(port uint, cache cache.SnapshotCache) {
+// Swap the cache the adapter sets state in, for when the management server
+// is started again with a new one.
+func SetCache(cache cache.SnapshotCache) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
 	xdsCache = cache
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	nodeKeys = map[string]map[string]bool{}
+	clearResourceVersions()
+}
+
+func currentCache() cache.SnapshotCache {
+	cacheMu.RLock()
+	defer cacheMu.RUnlock()
+	return xdsCache
+}
+
+func RunAdapter(port uint, cache cache.SnapshotCache) {
+	SetCache(cache)
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
@@ -51,12 +51,19 @@ func main() {
 	flag.Parse()
 
 	// Create a cache
-	cache := cache.NewSnapshotCache(false, cache.IDHash{}, l)
+	snapshotCache := example.NewVersionedCache(cache.NewSnapshotCache(false, example.IdentityHash{}, l), example.IdentityHash{})
 
-	// Run the xDS server
 	ctx := context.Background()
 	cb := &test.Callbacks{Debug: l.Debug}
-	srv := server.NewServer(ctx, cache, cb)
-	go example.RunAdapter(adapter, cache)
-	example.RunServer(ctx, srv, port)
+	go example.RunAdapter(adapter, snapshotCache)
+
+	// Run the xDS server. It only returns when the adapter restarts it, and
+	// then we start it again with an empty cache, like a control plane that
+	// lost its in-memory state.
+	for {
+		srv := server.NewServer(ctx, snapshotCache, cb)
+		example.RunServer(ctx, srv, port)
+		snapshotCache = example.NewVersionedCache(cache.NewSnapshotCache(false, example.IdentityHash{}, l), example.IdentityHash{})
+		example.SetCache(snapshotCache)
+	}
 }
@@ -18,6 +18,8 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"sync"
+	"time"
 
 	"google.golang.org/grpc"
 
@@ -35,6 +37,14 @@ const (
 	grpcMaxConcurrentStreams = 1000000
 )
 
+var (
+	// The running management server, kept so the adapter can restart it.
+	running   *grpc.Server
+	runningMu sync.Mutex
+	// Signalled each time a management server starts listening.
+	listening = make(chan bool, 1)
+)
+
 func registerServer(grpcServer *grpc.Server, server server.Server) {
 	// register services
 	discoverygrpc.RegisterAggregatedDiscoveryServiceServer(grpcServer, server)
@@ -63,8 +73,42 @@ func RunServer(ctx context.Context, srv server.Server, port uint) {
 
 	registerServer(grpcServer, srv)
 
+	runningMu.Lock()
+	running = grpcServer
+	runningMu.Unlock()
+	select {
+	case listening <- true:
+	default:
+	}
+
 	log.Printf("management server listening on %d\n", port)
 	if err = grpcServer.Serve(lis); err != nil {
 		log.Println(err)
 	}
 }
+
+// Stops the running management server, closing all of its streams, and waits
+// for the next one to start listening. RunServer returns once the server is
+// stopped, so it is up to its caller to start it again.
+func restartServer(timeout time.Duration) error {
+	runningMu.Lock()
+	grpcServer := running
+	runningMu.Unlock()
+	if grpcServer == nil {
+		return fmt.Errorf("management server is not running")
+	}
+
+	// clear any signal left from the server we are about to stop
+	select {
+	case <-listening:
+	default:
+	}
+	grpcServer.Stop()
+
+	select {
+	case <-listening:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("management server did not restart within %v", timeout)
+	}
+}
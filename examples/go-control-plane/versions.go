@@ -0,0 +1,117 @@
+package example
+
+import (
+	"sync"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/server/stream/v3"
+	pb "github.com/ii/xds-test-harness/api/adapter"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	// The versions SetState was given for individual resources, by cache key,
+	// type url and resource name.
+	versionsMu       sync.Mutex
+	resourceVersions = map[string]map[string]map[string]string{}
+)
+
+// Replaces the resource versions of the cache key with the given ones.
+func setResourceVersions(key string, versions []*pb.ResourceVersion) {
+	versionsMu.Lock()
+	defer versionsMu.Unlock()
+	delete(resourceVersions, key)
+	for _, rv := range versions {
+		if resourceVersions[key] == nil {
+			resourceVersions[key] = make(map[string]map[string]string)
+		}
+		if resourceVersions[key][rv.TypeUrl] == nil {
+			resourceVersions[key][rv.TypeUrl] = make(map[string]string)
+		}
+		resourceVersions[key][rv.TypeUrl][rv.ResourceName] = rv.Version
+	}
+}
+
+// Drops the version SetState gave a resource, once it has changed since.
+func forgetResourceVersion(key, typeUrl, name string) {
+	versionsMu.Lock()
+	defer versionsMu.Unlock()
+	delete(resourceVersions[key][typeUrl], name)
+}
+
+func clearResourceVersions() {
+	versionsMu.Lock()
+	defer versionsMu.Unlock()
+	resourceVersions = map[string]map[string]map[string]string{}
+}
+
+func resourceVersion(key, typeUrl, name string) (string, bool) {
+	versionsMu.Lock()
+	defer versionsMu.Unlock()
+	version, ok := resourceVersions[key][typeUrl][name]
+	return version, ok
+}
+
+// Wraps a snapshot cache so its delta responses carry the resource versions
+// SetState was given. go-control-plane versions each resource it sends with
+// a hash of it, and uses the same hashes to tell what changed, so the
+// versions are only swapped into the responses on their way out.
+type VersionedCache struct {
+	cache.SnapshotCache
+	hash cache.NodeHash
+}
+
+func NewVersionedCache(c cache.SnapshotCache, hash cache.NodeHash) *VersionedCache {
+	return &VersionedCache{SnapshotCache: c, hash: hash}
+}
+
+func (c *VersionedCache) CreateDeltaWatch(request *cache.DeltaRequest, state stream.StreamState, value chan cache.DeltaResponse) func() {
+	key := c.hash.ID(request.Node)
+	// the cache may respond before returning, so this needs room for it.
+	responses := make(chan cache.DeltaResponse, 1)
+	done := make(chan struct{})
+	cancel := c.SnapshotCache.CreateDeltaWatch(request, state, responses)
+	go func() {
+		select {
+		case response := <-responses:
+			value <- &versionedResponse{DeltaResponse: response, key: key}
+		case <-done:
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		if cancel != nil {
+			cancel()
+		}
+	}
+}
+
+type versionedResponse struct {
+	cache.DeltaResponse
+	key      string
+	once     sync.Once
+	response *discovery.DeltaDiscoveryResponse
+	err      error
+}
+
+// The cache's response, with the resources SetState gave a version to at
+// that version rather than their hash. It's built once, as the server sets
+// the nonce on the response it gets.
+func (r *versionedResponse) GetDeltaDiscoveryResponse() (*discovery.DeltaDiscoveryResponse, error) {
+	r.once.Do(func() {
+		response, err := r.DeltaResponse.GetDeltaDiscoveryResponse()
+		if err != nil {
+			r.err = err
+			return
+		}
+		r.response = proto.Clone(response).(*discovery.DeltaDiscoveryResponse)
+		for _, resource := range r.response.Resources {
+			if version, ok := resourceVersion(r.key, r.response.TypeUrl, resource.Name); ok {
+				resource.Version = version
+			}
+		}
+	})
+	return r.response, r.err
+}
@@ -0,0 +1,62 @@
+Feature: Multiple Clients
+  Control planes often share watches between clients on the same node.
+  Every client should receive the updates it subscribed to, and one client
+  changing its subscription should not change what another receives.
+
+  @spec:resource-updates @must
+  @sotw @incremental @non-aggregated @aggregated
+  Scenario Outline: [<xDS>] An update reaches every subscribed client
+    Given a target setup with service <xDS>, resources <resources>, and starting version <v1>
+    When Client "A" subscribes to resources <resources> for <xDS>
+    And Client "B" subscribes to resources <resources> for <xDS>
+    And Client "C" subscribes to resources <resources> for <xDS>
+    Then every Client receives the resources <resources> and version <v1> for <xDS>
+    When the resource <r1> of service <xDS> is updated to version <v2>
+    Then every Client receives the resources <r1> and version <v2> for <xDS>
+
+    Examples:
+      | xDS   | resources | r1  | v1  | v2  |
+      | "CDS" | "A,B"     | "A" | "1" | "2" |
+      | "LDS" | "A,B"     | "A" | "1" | "2" |
+      | "RDS" | "A,B"     | "A" | "1" | "2" |
+      | "EDS" | "A,B"     | "A" | "1" | "2" |
+
+
+  @spec:unsubscribing @must
+  @sotw @non-aggregated @aggregated
+  Scenario Outline: [<xDS>] A client unsubscribing does not stop updates to another
+    Given a target setup with service <xDS>, resources <resources>, and starting version <v1>
+    When Client "A" subscribes to resources <resources> for <xDS>
+    And Client "B" subscribes to resources <resources> for <xDS>
+    Then every Client receives the resources <resources> and version <v1> for <xDS>
+    When Client "A" unsubscribes from all resources for <xDS>
+    And the resource <r1> of service <xDS> is updated to version <v2>
+    Then Client "B" receives the resources <r1> and version <v2> for <xDS>
+    And Client "A" does not receive any message from <xDS>
+
+    Examples:
+      | xDS   | resources | r1  | v1  | v2  |
+      | "CDS" | "A,B"     | "A" | "1" | "2" |
+      | "LDS" | "A,B"     | "A" | "1" | "2" |
+      | "RDS" | "A,B"     | "A" | "1" | "2" |
+      | "EDS" | "A,B"     | "A" | "1" | "2" |
+
+
+  @spec:unsubscribing @must
+  @incremental @non-aggregated @aggregated
+  Scenario Outline: [<xDS>] A client unsubscribing from a resource does not stop updates to another
+    Given a target setup with service <xDS>, resources <resources>, and starting version <v1>
+    When Client "A" subscribes to resources <resources> for <xDS>
+    And Client "B" subscribes to resources <resources> for <xDS>
+    Then every Client receives the resources <resources> and version <v1> for <xDS>
+    When Client "A" unsubscribes from resource <r1> for service <xDS>
+    And the resource <r1> of service <xDS> is updated to version <v2>
+    Then Client "B" receives the resources <r1> and version <v2> for <xDS>
+    And Client "A" does not receive resource <r1> of service <xDS> at version <v2>
+
+    Examples:
+      | xDS   | resources | r1  | v1  | v2  |
+      | "CDS" | "A,B"     | "A" | "1" | "2" |
+      | "LDS" | "A,B"     | "A" | "1" | "2" |
+      | "RDS" | "A,B"     | "A" | "1" | "2" |
+      | "EDS" | "A,B"     | "A" | "1" | "2" |
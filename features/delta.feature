@@ -2,6 +2,7 @@ Feature: Delta
   Client can subscribe and unsubscribe using the incremental variant
 
 
+  @spec:incremental-updates @must
   @incremental @non-aggregated @aggregated
   Scenario Outline: [<xDS>] Subscribe to resources one after the other
     Given a target setup with service <xDS>, resources <resources>, and starting version <v1>
@@ -19,6 +20,7 @@ Feature: Delta
       | "EDS" | "D,E,F"   | "D" | "E" | "1" |
 
 
+ @spec:incremental-updates @must
  @incremental @non-aggregated @aggregated
  Scenario Outline: [<xDS>] When a resource is updated, receive response for only that resource
     Given a target setup with service <xDS>, resources <resources>, and starting version <v1>
@@ -36,6 +38,7 @@ Feature: Delta
       | "RDS" | "A,B,C"   | "A" | "1" | "2" |
       | "EDS" | "A,B,C"   | "A" | "1" | "2" |
 
+ @spec:resource-does-not-exist @must
  @incremental @non-aggregated @aggregated
  Scenario Outline: [<xDS>] Client is told if resource does not exist, and is notified if it is created
    Given a target setup with service <xDS>, resources <r1>, and starting version <v1>
@@ -56,6 +59,7 @@ Feature: Delta
      | "EDS" | "1" | "D,E"     | "D" | "E" |
 
 
+ @spec:deleting-resources @must
  @incremental @non-aggregated @aggregated
  Scenario Outline: [<xDS>] Client is told when a resource is removed via removed_resources field
    Given a target setup with service <xDS>, resources <resources>, and starting version <v1>
@@ -76,6 +80,7 @@ Feature: Delta
      | "EDS" | "D,E"     | "E" | "1" | "2" |
 
 
+ @spec:unsubscribing @must
  @incremental @non-aggregated @aggregated
  Scenario Outline: [<xDS>] Client can incrementally unsubscribe from resources
    Given a target setup with service <xDS>, resources <resources>, and starting version <v1>
@@ -96,6 +101,7 @@ Feature: Delta
      | "RDS" | "D,E"     | "E" | "D" | "1" | "2" |
      | "EDS" | "D,E"     | "E" | "D" | "1" | "2" |
 
+  @spec:ads @must
   @incremental @aggregated
   Scenario Outline: [<services>] Client can subscribe to multiple services via ADS
     Given a target setup with multiple services <services>, each with resources <resources>, and starting version <v1>
@@ -115,3 +121,23 @@ Feature: Delta
       | "LDS,CDS" | "LDS" | "CDS" | "A,B,C"   | "B" | "1" | "2" |
       | "RDS,EDS" | "RDS" | "EDS" | "A,B,C"   | "B" | "1" | "2" |
       | "EDS,RDS" | "EDS" | "RDS" | "A,B,C"   | "B" | "1" | "2" |
+
+  @spec:delta-resource-versions @must
+  @incremental @non-aggregated @aggregated
+  Scenario Outline: [<xDS>] Client receives the version of each resource
+    Given a target setup with the following state:
+      | service | resources | version | resource version |
+      | <xDS>   | <r1>      | <v1>    | <v1>             |
+      | <xDS>   | <r2>      | <v1>    | <v2>             |
+    When the Client subscribes to resources <resources> for <xDS>
+    Then the Client receives the resources <resources> and version <v1> for <xDS>
+     And the Client receives the resource <r1> with resource version <v1> for <xDS>
+     And the Client receives the resource <r2> with resource version <v2> for <xDS>
+     And the service never responds more than necessary
+
+    Examples:
+      | xDS   | resources | r1  | r2  | v1  | v2  |
+      | "CDS" | "A,B"     | "A" | "B" | "1" | "2" |
+      | "LDS" | "D,E"     | "D" | "E" | "1" | "2" |
+      | "RDS" | "D,E"     | "D" | "E" | "1" | "2" |
+      | "EDS" | "D,E"     | "D" | "E" | "1" | "2" |
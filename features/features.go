@@ -0,0 +1,8 @@
+// Package features embeds the feature files, so the harness can run them
+// without a copy of this repo beside it.
+package features
+
+import "embed"
+
+//go:embed *.feature requirements.yaml
+var FS embed.FS
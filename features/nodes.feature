@@ -0,0 +1,113 @@
+Feature: Nodes
+  A server keeps its state per node. Clients on different nodes should only
+  ever receive the resources set for their own node, even as the state of
+  another node changes. Clients can send more than their node's id, and a
+  server that chooses resources by a node's cluster, locality or metadata
+  should serve clients sharing a node id only what was set for their identity.
+  The server should also handle the node being sent only on the first request.
+
+  @spec:client-identity @should
+  @sotw @incremental @non-aggregated @aggregated
+  Scenario Outline: [<xDS>] Clients on different nodes only receive their own resources
+    Given Client "edge" with node "edge-node"
+    And Client "core" with node "core-node"
+    And a target setup for node "edge-node" with service <xDS>, resources <r1>, and starting version <v1>
+    And a target setup for node "core-node" with service <xDS>, resources <r2>, and starting version <v1>
+    When Client "edge" subscribes to resources <resources> for <xDS>
+    And Client "core" subscribes to resources <resources> for <xDS>
+    Then Client "edge" receives the resources <r1> and version <v1> for <xDS>
+    And Client "core" receives the resources <r2> and version <v1> for <xDS>
+    And Client "edge" receives no resources other than <r1> for <xDS>
+    And Client "core" receives no resources other than <r2> for <xDS>
+
+    Examples:
+      | xDS   | resources | r1  | r2  | v1  |
+      | "CDS" | "A,B"     | "A" | "B" | "1" |
+      | "LDS" | "A,B"     | "A" | "B" | "1" |
+      | "RDS" | "A,B"     | "A" | "B" | "1" |
+      | "EDS" | "A,B"     | "A" | "B" | "1" |
+
+
+  @spec:client-identity @should
+  @sotw @incremental @non-aggregated @aggregated
+  Scenario Outline: [<xDS>] Updates to one node do not reach clients on another
+    Given Client "edge" with node "edge-node"
+    And Client "core" with node "core-node"
+    And a target setup for node "edge-node" with service <xDS>, resources <resources>, and starting version <v1>
+    And a target setup for node "core-node" with service <xDS>, resources <resources>, and starting version <v1>
+    When Client "edge" subscribes to resources <subset> for <xDS>
+    And Client "core" subscribes to resources <subset> for <xDS>
+    Then Client "edge" receives the resources <resources> and version <v1> for <xDS>
+    And Client "core" receives the resources <resources> and version <v1> for <xDS>
+    When the resource <r1> is added to the <xDS> with version <v2> for node "edge-node"
+    Then Client "edge" receives the resources <r1> and version <v2> for <xDS>
+    And Client "core" receives no resources other than <resources> for <xDS>
+
+    Examples:
+      | xDS   | resources | subset  | r1  | v1  | v2  |
+      | "CDS" | "A,B"     | "A,B,C" | "C" | "1" | "2" |
+      | "LDS" | "A,B"     | "A,B,C" | "C" | "1" | "2" |
+      | "RDS" | "A,B"     | "A,B,C" | "C" | "1" | "2" |
+      | "EDS" | "A,B"     | "A,B,C" | "C" | "1" | "2" |
+
+
+  @spec:client-identity @should
+  @sotw @incremental @non-aggregated @aggregated
+  Scenario Outline: [<xDS>] Clients sharing a node id but not an identity receive the resources set for them
+    Given Client "east" with node "shared-node" and identity:
+      """
+      cluster: edge
+      userAgentName: envoy
+      locality:
+        region: us-east
+      metadata:
+        tier: gold
+      """
+    And Client "west" with node "shared-node" and identity:
+      """
+      cluster: edge
+      userAgentName: envoy
+      locality:
+        region: us-west
+      metadata:
+        tier: silver
+      """
+    And a target setup for Client "east" with service <xDS>, resources <r1>, and starting version <v1>
+    And a target setup for Client "west" with service <xDS>, resources <r2>, and starting version <v1>
+    When Client "east" subscribes to resources <resources> for <xDS>
+    And Client "west" subscribes to resources <resources> for <xDS>
+    Then Client "east" receives the resources <r1> and version <v1> for <xDS>
+    And Client "west" receives the resources <r2> and version <v1> for <xDS>
+    And Client "east" receives no resources other than <r1> for <xDS>
+    And Client "west" receives no resources other than <r2> for <xDS>
+
+    Examples:
+      | xDS   | resources | r1  | r2  | v1  |
+      | "CDS" | "A,B"     | "A" | "B" | "1" |
+      | "LDS" | "A,B"     | "A" | "B" | "1" |
+      | "RDS" | "A,B"     | "A" | "B" | "1" |
+      | "EDS" | "A,B"     | "A" | "B" | "1" |
+
+
+  @spec:node-first-request @must
+  @sotw @incremental @non-aggregated @aggregated
+  Scenario Outline: [<xDS>] Server handles a node sent only on the first request of a stream
+    Given the Client has the node identity:
+      """
+      cluster: edge
+      userAgentName: envoy
+      userAgentVersion: 1.22.0
+      """
+    And a target setup with service <xDS>, resources <resources>, and starting version <v1>
+    And the Client sends its node only on the first request
+    When the Client subscribes to resources <r1> for <xDS>
+    Then the Client receives the resources <r1> and version <v1> for <xDS>
+    When the Client subscribes to resources <r2> for <xDS>
+    Then the Client receives the resources <r2> and version <v1> for <xDS>
+
+    Examples:
+      | xDS   | resources | r1  | r2  | v1  |
+      | "CDS" | "A,B,C"   | "A" | "B" | "1" |
+      | "LDS" | "D,E,F"   | "D" | "E" | "1" |
+      | "RDS" | "D,E,F"   | "D" | "E" | "1" |
+      | "EDS" | "D,E,F"   | "D" | "E" | "1" |
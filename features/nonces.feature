@@ -0,0 +1,42 @@
+Feature: Stale Nonces
+  Each request a client sends carries the nonce of the response it last
+  received. A request whose nonce is from an earlier response, or was never
+  sent at all, is stale: it was written before the client saw the latest
+  response. The server should ignore it, neither answering it nor changing
+  the client's subscription because of it.
+
+  Incremental servers may take subscription changes whatever the nonce, so
+  these scenarios are for sotw streams only.
+
+  @spec:stale-nonce @should
+  @sotw @non-aggregated @aggregated
+  Scenario Outline: [<xDS>] Server ignores a request with the nonce of an earlier response
+    Given a target setup with service <xDS>, resources <resources>, and starting version <v1>
+    When the Client subscribes to resources <r1> for <xDS>
+    Then the Client receives the resources <r1> and version <v1> for <xDS>
+    When the resource <r1> of service <xDS> is updated to version <v2>
+    Then the Client receives the resources <r1> and version <v2> for <xDS>
+    When the Client sends a request for <resources> of <xDS> with the nonce of response 1
+    Then the Client receives no response to it for <xDS>
+    When the resource <r2> of service <xDS> is updated to version <v3>
+    Then the client does not receive resource <r2> of service <xDS> at version <v3>
+
+    Examples:
+      | xDS   | resources | r1  | r2  | v1  | v2  | v3  |
+      | "CDS" | "A,B"     | "A" | "B" | "1" | "2" | "3" |
+      | "LDS" | "D,E"     | "D" | "E" | "1" | "2" | "3" |
+
+
+  @spec:stale-nonce @should
+  @sotw @non-aggregated @aggregated
+  Scenario Outline: [<xDS>] Server ignores a request with a nonce it never sent
+    Given a target setup with service <xDS>, resources <resources>, and starting version <v1>
+    When the Client subscribes to resources <r1> for <xDS>
+    Then the Client receives the resources <r1> and version <v1> for <xDS>
+    When the Client sends a request for <resources> of <xDS> with the nonce "not-a-nonce"
+    Then the Client receives no response to it for <xDS>
+
+    Examples:
+      | xDS   | resources | r1  | v1  |
+      | "CDS" | "A,B"     | "A" | "1" |
+      | "LDS" | "D,E"     | "D" | "1" |
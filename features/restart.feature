@@ -0,0 +1,46 @@
+Feature: Restarting the Target
+  When a management server restarts it loses its streams and its in-memory
+  state. Clients reconnect and resubscribe, and once the state is set again
+  they should be sent their resources, even if the version has gone back
+  to where it started. Nonces are only unique to a stream, so the restarted
+  target may send the same ones again, but a nonce from before the restart
+  that it hasn't is stale, and the target should ignore a request with it.
+
+  These tests need the adapter to implement the optional Restart call.
+  If it does not, they are left pending, which doesn't fail the run.
+
+  @spec:reconnect @should @exclusive
+  @sotw @incremental @non-aggregated @aggregated
+  Scenario Outline: [<xDS>] Client receives its resources again after the target restarts
+    Given a target setup with service <xDS>, resources <resources>, and starting version <v1>
+    When the Client subscribes to resources <resources> for <xDS>
+    Then the Client receives the resources <resources> and version <v1> for <xDS>
+    When the target restarts
+    And a target setup with service <xDS>, resources <resources>, and starting version <v2>
+    Then the Client receives the resources <resources> and version <v2> for <xDS>
+
+    Examples:
+      | xDS   | resources | v1  | v2  |
+      | "CDS" | "A,B"     | "2" | "1" |
+      | "LDS" | "D,E"     | "2" | "1" |
+      | "RDS" | "D,E"     | "1" | "2" |
+      | "EDS" | "D,E"     | "1" | "2" |
+
+  @spec:stale-nonce @should @exclusive
+  @sotw @non-aggregated @aggregated
+  Scenario Outline: [<xDS>] Server ignores a nonce from before it restarted
+    Given a target setup with service <xDS>, resources <resources>, and starting version <v1>
+    When the Client subscribes to resources <r1> for <xDS>
+    Then the Client receives the resources <r1> and version <v1> for <xDS>
+    When the resource <r1> of service <xDS> is updated to version <v2>
+    Then the Client receives the resources <r1> and version <v2> for <xDS>
+    When the target restarts
+    And a target setup with service <xDS>, resources <resources>, and starting version <v1>
+    Then the Client receives the resources <r1> and version <v1> for <xDS>
+    When the Client sends a request for <resources> of <xDS> with a nonce from before the restart
+    Then the Client receives no response to it for <xDS>
+
+    Examples:
+      | xDS   | resources | r1  | v1  | v2  |
+      | "CDS" | "A,B"     | "A" | "1" | "2" |
+      | "LDS" | "D,E"     | "D" | "1" | "2" |
@@ -0,0 +1,39 @@
+Feature: Resources at Scale
+  Real deployments set thousands of resources, far more than the handful the
+  other features name. A client should be sent every one of them, in responses
+  that fit the largest gRPC message it accepts. That's 4MB unless the harness
+  is run with --max-recv-msg-size, or a scenario sets its own.
+
+  A sotw response has the whole state, so it can't be split to fit a smaller
+  limit, and go-control-plane doesn't split incremental ones either. So with
+  a limit of 256KB, the state is sized to fit in one response: 1000 example
+  clusters come to about 210KB in an incremental response.
+
+  These scenarios are tagged @scale, so a quick run can leave them out with
+  -t "~@scale".
+
+  @spec:wildcard @should @scale
+  @sotw @incremental @non-aggregated @aggregated
+  Scenario Outline: [<xDS>] Client receives a large state in full
+    Given a target setup with <count> <xDS> resources
+    When the Client does a wildcard subscription to <xDS>
+    Then the Client receives all <count> resources for <xDS> within 30s
+
+    Examples:
+      | xDS   | count |
+      | "CDS" | 10000 |
+      | "LDS" | 10000 |
+
+  @spec:wildcard @should @scale
+  @sotw @incremental @non-aggregated @aggregated
+  Scenario Outline: [<xDS>] Client receives a state that fits in a response it accepts
+    Given the Client accepts messages of at most 256KB
+    And a target setup with <count> <xDS> resources
+    When the Client does a wildcard subscription to <xDS>
+    Then the Client receives all <count> resources for <xDS> within 30s
+    And every response for <xDS> was at most 256KB
+
+    Examples:
+      | xDS   | count |
+      | "CDS" | 1000  |
+      | "LDS" | 1000  |
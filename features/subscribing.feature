@@ -5,6 +5,7 @@ Feature: Subscribing to Resources
   These features come from this list of test cases:
   https://docs.google.com/document/d/19oUEt9jSSgwNnvZjZgaFYBHZZsw52f2MwSo6LWKzg-E
 
+  @spec:wildcard @spec:minimal-responses @must
   @sotw @non-aggregated @aggregated
   Scenario Outline: [<xDS>] The service should send all resources on a wildcard request.
     Given a target setup with service <xDS>, resources <resources>, and starting version <v1>
@@ -19,6 +20,7 @@ Feature: Subscribing to Resources
       | "LDS" | "D,E,F"   | "F,D,E"  | "1" |
 
 
+  @spec:resource-updates @spec:minimal-responses @must
   @sotw @non-aggregated @aggregated
   Scenario Outline: [<xDS>] The service should send updates to the client
     Given a target setup with service <xDS>, resources <resources>, and starting version <v1>
@@ -35,6 +37,7 @@ Feature: Subscribing to Resources
       | "LDS" | "D,E,F"   | "D" | "1" | "2" |
 
 
+  @spec:wildcard @spec:resource-updates @must
   @sotw @non-aggregated @aggregated
   Scenario Outline: [<xDS>] Wildcard subscriptions receive updates when new resources are added
     Given a target setup with service <xDS>, resources <resources>, and starting version <v1>
@@ -51,6 +54,7 @@ Feature: Subscribing to Resources
       | "LDS" | "D,E,F"   | "G" | "D,E,F,G" | "1" | "2" |
 
 
+  @spec:named-subscriptions @must
   @sotw @incremental @non-aggregated @aggregated
   Scenario Outline: [<xDS>]  When subscribing to specific resources, receive only these resources
     Given a target setup with service <xDS>, resources <resources>, and starting version <v1>
@@ -66,6 +70,7 @@ Feature: Subscribing to Resources
       # | "EDS" | "A,B"     | "A,B"  | "1" |
 
 
+  @spec:named-subscriptions @spec:resource-updates @must
   @sotw @non-aggregated @aggregated
   Scenario Outline: [<xDS>] When subscribing to specific resources, receive response when those resources change
     Given a target setup with service <xDS>, resources <resources>, and starting version <v1>
@@ -82,6 +87,7 @@ Feature: Subscribing to Resources
       | "LDS" | "G,B,L,D" | "L,G"  | "G" | "1" | "2" |
 
 
+  @spec:named-subscriptions @spec:resource-updates @must
   @sotw @non-aggregated @aggregated
   Scenario Outline: [<xDS>] When subscribing to specific resources, receive response when those resources change
     Given a target setup with service <xDS>, resources <resources>, and starting version <v1>
@@ -97,6 +103,7 @@ Feature: Subscribing to Resources
       | "EDS" | "A,B,C,D" | "B,D"  | "B" | "1" | "2" |
 
 
+  @spec:resource-does-not-exist @must
   @sotw @non-aggregated @aggregated
   Scenario Outline: [<xDS>] When subscribing to resources that don't exist, receive response when they are created
     Given a target setup with service <xDS>, resources <resources>, and starting version <v1>
@@ -113,6 +120,7 @@ Feature: Subscribing to Resources
       | "LDS" | "G,B,D"   | "B,D,X" | "B,D"           | "X" | "1" | "2" |
 
 
+  @spec:resource-does-not-exist @must
   @sotw @non-aggregated @aggregated
   Scenario Outline: [<xDS>] When subscribing to resources that don't exist, receive response when they are created
     Given a target setup with service <xDS>, resources <resources>, and starting version <v1>
@@ -128,6 +136,7 @@ Feature: Subscribing to Resources
       | "EDS" | "A,B,C,D" | "A,Z"  | "A"             | "Z" | "1" | "2" |
 
 
+  @spec:ads @must
   @sotw @aggregated
   Scenario Outline: [<services>] Client can subscribe to multiple services via ADS
     Given a target setup with multiple services <services>, each with resources <resources>, and starting version <v1>
@@ -143,3 +152,22 @@ Feature: Subscribing to Resources
       | services  | xDS   | xds2  | resources | r1  | v1  | v2  |
       | "CDS,LDS" | "CDS" | "LDS" | "A,B,C"   | "B" | "1" | "2" |
       | "RDS,EDS" | "RDS" | "EDS" | "A,B,C"   | "B" | "1" | "2" |
+
+
+  @spec:ads @must
+  @sotw @aggregated
+  Scenario Outline: [<xDS>,<xds2>] Services can each be set at their own version
+    Given a target setup with the following state:
+      | service | resources | version |
+      | <xDS>   | <r1>      | <v1>    |
+      | <xds2>  | <r1>      | <v2>    |
+    When the Client subscribes to resources <r1> for <xDS>
+    Then the Client receives the resources <r1> and version <v1> for <xDS>
+    When the Client subscribes to resources <r1> for <xds2>
+    Then the Client receives the resources <r1> and version <v2> for <xds2>
+    And the service never responds more than necessary
+
+    Examples:
+      | xDS   | xds2  | r1  | v1  | v2  |
+      | "CDS" | "LDS" | "A" | "1" | "3" |
+      | "RDS" | "EDS" | "A" | "2" | "1" |
@@ -6,6 +6,7 @@ Feature: Unsubscribing to Resources
   These features come from this list of test cases:
   https://docs.google.com/document/d/19oUEt9jSSgwNnvZjZgaFYBHZZsw52f2MwSo6LWKzg-E
 
+  @spec:unsubscribing @must
   @sotw @non-aggregated @aggregated
   Scenario Outline: [<xDS>] Client can unsubcribe from some resources
     # This test does not check if the final results are only the subscribed resources
@@ -26,6 +27,7 @@ Feature: Unsubscribing to Resources
       | "LDS" | "G,B,L,D"   | "B,D,L" | "B" | "1" | "2" |
 
 
+  @spec:unsubscribing @must
   @sotw @non-aggregated @aggregated
   Scenario Outline: [<xDS>] Client can unsubcribe from some resources
     # difference from test above is use of the word ONLY in the final THEN step
@@ -43,6 +45,7 @@ Feature: Unsubscribing to Resources
       | "EDS" | "A,B,C,D" | "B,D"  | "B" | "1" | "2" |
 
 
+  @spec:unsubscribing @must
   @sotw @non-aggregated @aggregated
   Scenario Outline: [<xDS>] Client can unsubscribe from all resources
     Given a target setup with service <xDS>, resources <resources>, and starting version <v1>
@@ -5,30 +5,47 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/cucumber/godog"
 	"github.com/cucumber/godog/colors"
+	"github.com/ii/xds-test-harness/internal/runner"
 	"github.com/ii/xds-test-harness/internal/types"
 	"github.com/rs/zerolog/log"
 )
 
+// The features directory of each suite, by name, so feature uris can be given as
+// they are in the repo. Filled in before any suite runs.
+var suiteFeatures = map[string]string{}
+
 func init() {
 	godog.Format("xds", "Progress formatter with emojis", xdsFormatterFunc)
 }
 
 func xdsFormatterFunc(suite string, out io.Writer) godog.Formatter {
-	return newxdsFmt(suite, out)
+	f := newxdsFmt(suite, out)
+	f.features = suiteFeatures[suite]
+	return f
 }
 
 type xdsFmt struct {
 	*godog.ProgressFmt
-	out     io.Writer
-	results types.VariantResults
+	out      io.Writer
+	suite    string
+	features string
+	results  types.VariantResults
+	// The pickle each of the results' scenarios ran, in the same order.
+	pickles []string
+	// Every scenario's push latency samples, for the variant's.
+	pushSamples []time.Duration
 }
 
 func newxdsFmt(suite string, out io.Writer) *xdsFmt {
 	return &xdsFmt{
 		ProgressFmt: godog.NewProgressFmt(suite, out),
+		suite:       suite,
 		out:         out,
 		results:     types.VariantResults{},
 	}
@@ -38,8 +55,8 @@ func (f *xdsFmt) Passed(scenario *godog.Scenario, step *godog.Step, match *godog
 	f.ProgressFmt.Base.Passed(scenario, step, match)
 	f.ProgressFmt.Base.Lock.Lock()
 	defer f.ProgressFmt.Base.Lock.Unlock()
-	f.results.Total++
-	f.results.Passed++
+	f.results.Steps.Total++
+	f.results.Steps.Passed++
 	f.step(step.Id, scenario)
 }
 
@@ -47,8 +64,8 @@ func (f *xdsFmt) Skipped(scenario *godog.Scenario, step *godog.Step, match *godo
 	f.ProgressFmt.Base.Skipped(scenario, step, match)
 	f.ProgressFmt.Base.Lock.Lock()
 	defer f.ProgressFmt.Base.Lock.Unlock()
-	f.results.Total++
-	f.results.Skipped++
+	f.results.Steps.Total++
+	f.results.Steps.Skipped++
 	f.step(step.Id, scenario)
 }
 
@@ -56,8 +73,8 @@ func (f *xdsFmt) Undefined(scenario *godog.Scenario, step *godog.Step, match *go
 	f.ProgressFmt.Base.Undefined(scenario, step, match)
 	f.ProgressFmt.Base.Lock.Lock()
 	defer f.ProgressFmt.Base.Lock.Unlock()
-	f.results.Total++
-	f.results.Undefined++
+	f.results.Steps.Total++
+	f.results.Steps.Undefined++
 	f.step(step.Id, scenario)
 }
 
@@ -65,8 +82,8 @@ func (f *xdsFmt) Failed(scenario *godog.Scenario, step *godog.Step, match *godog
 	f.ProgressFmt.Base.Failed(scenario, step, match, err)
 	f.ProgressFmt.Base.Lock.Lock()
 	defer f.ProgressFmt.Base.Lock.Unlock()
-	f.results.Total++
-	f.results.Failed++
+	f.results.Steps.Total++
+	f.results.Steps.Failed++
 	f.step(step.Id, scenario)
 }
 
@@ -74,13 +91,15 @@ func (f *xdsFmt) Pending(scenario *godog.Scenario, step *godog.Step, match *godo
 	f.ProgressFmt.Base.Pending(scenario, step, match)
 	f.ProgressFmt.Base.Lock.Lock()
 	defer f.ProgressFmt.Base.Lock.Unlock()
-	f.results.Total++
-	f.results.Pending++
+	f.results.Steps.Total++
+	f.results.Steps.Pending++
 	f.step(step.Id, scenario)
 }
 
 func (f *xdsFmt) Summary() {
+	f.sortScenarios()
 	f.results.FailedScenarios = f.gatherFailedScenarios()
+	f.results.PushLatency = types.NewPushLatency(f.pushSamples)
 	data, err := json.MarshalIndent(f.results, "", "  ")
 	if err != nil {
 		panic(err)
@@ -94,12 +113,18 @@ func (f *xdsFmt) step(pickleStepID string, scenario *godog.Scenario) {
 
 	lastStep := isLastStep(pickleStepID, scenario)
 	if lastStep {
-		failed, failedStep, err := f.didScenarioFail(scenario)
-		if failed {
+		result := f.scenarioResult(scenario, pickleStepResult.FinishedAt)
+		measured := runner.TakeMeasurements(f.suite, scenario.Id)
+		result.PushLatency = types.NewPushLatency(measured.PushSamples)
+		result.SnapshotDelivery = measured.SnapshotDelivery
+		f.pushSamples = append(f.pushSamples, measured.PushSamples...)
+		f.countScenario(result)
+		f.pickles = append(f.pickles, scenario.Id)
+		if result.Status == godog.StepFailed.String() {
 			log.Info().
-				Str("failed step", failedStep).
+				Str("failed step", result.FailedStep).
 				Msgf("| [%v]%v", colors.Red("FAILED"), scenario.Name)
-			log.Err(errors.New(err)).Msg("")
+			log.Err(errors.New(result.Error)).Msg("")
 
 		} else {
 			log.Info().
@@ -109,38 +134,108 @@ func (f *xdsFmt) step(pickleStepID string, scenario *godog.Scenario) {
 	*f.Steps++
 }
 
-func (f *xdsFmt) didScenarioFail(scenario *godog.Scenario) (failed bool, failedStep string, err string) {
-	failed = false
-	results := f.Storage.MustGetPickleStepResultsByPickleID(scenario.Id)
-	for _, result := range results {
-		if result.Status.String() == "failed" {
-			feature := f.Storage.MustGetFeature(scenario.Uri)
-			pickleStep := f.Storage.MustGetPickleStep(result.PickleStepID)
-			step := feature.FindStep(pickleStep.AstNodeIds[0])
-			failed = true
-			failedStep = step.Text
-			err = result.Err.Error()
-		}
+func (f *xdsFmt) countScenario(result types.ScenarioResult) {
+	f.results.Scenarios = append(f.results.Scenarios, result)
+	f.results.Total++
+	switch result.Status {
+	case godog.StepPassed.String():
+		f.results.Passed++
+	case godog.StepFailed.String():
+		f.results.Failed++
+	case godog.StepSkipped.String():
+		f.results.Skipped++
+	case godog.StepUndefined.String():
+		f.results.Undefined++
+	case godog.StepPending.String():
+		f.results.Pending++
 	}
-	return failed, failedStep, err
 }
 
-func (f *xdsFmt) gatherFailedScenarios() (failedScenarios []types.FailedScenario) {
-	failedSteps := f.Storage.MustGetPickleStepResultsByStatus(1)
-	for _, failure := range failedSteps {
-		scenario := f.Storage.MustGetPickle(failure.PickleID)
-		feature := f.Storage.MustGetFeature(scenario.Uri)
-		pickleStep := f.Storage.MustGetPickleStep(failure.PickleStepID)
+// Sums up the scenario from the results of its steps. A scenario is as bad as its
+// worst step, so a single failed step fails it, then undefined, pending, and skipped.
+func (f *xdsFmt) scenarioResult(scenario *godog.Scenario, finishedAt time.Time) types.ScenarioResult {
+	feature := f.Storage.MustGetFeature(scenario.Uri)
+	line, example := runner.ScenarioLocation(feature, scenario)
+	result := types.ScenarioResult{
+		Name:    scenario.Name,
+		Feature: runner.FeatureURI(f.features, scenario.Uri),
+		Line:    line,
+		Example: example,
+		Status:  godog.StepPassed.String(),
+	}
+	result.ID = types.ScenarioID(result.Feature, result.Name, result.Example)
+	for _, tag := range scenario.Tags {
+		result.Tags = append(result.Tags, tag.Name)
+	}
+	started := f.Storage.MustGetPickleResult(scenario.Id).StartedAt
+	result.Duration = finishedAt.Sub(started)
+
+	worst := map[string]int{
+		godog.StepPassed.String():    0,
+		godog.StepSkipped.String():   1,
+		godog.StepPending.String():   2,
+		godog.StepUndefined.String(): 3,
+		godog.StepFailed.String():    4,
+	}
+	for _, stepResult := range f.Storage.MustGetPickleStepResultsByPickleID(scenario.Id) {
+		status := stepResult.Status.String()
+		if worst[status] > worst[result.Status] {
+			result.Status = status
+		}
+		if stepResult.Status == godog.StepFailed {
+			pickleStep := f.Storage.MustGetPickleStep(stepResult.PickleStepID)
+			step := feature.FindStep(pickleStep.AstNodeIds[0])
+			result.FailedStep = step.Text
+			result.FailedStepLine = int(step.Location.Line)
+			result.Error = stepResult.Err.Error()
+		}
+	}
+	for _, pickleStep := range scenario.Steps {
+		stepResult := f.Storage.MustGetPickleStepResult(pickleStep.Id)
 		step := feature.FindStep(pickleStep.AstNodeIds[0])
+		sr := types.StepResult{
+			Keyword: strings.TrimSpace(step.Keyword),
+			Text:    pickleStep.Text,
+			Line:    int(step.Location.Line),
+			Status:  stepResult.Status.String(),
+		}
+		if stepResult.Err != nil {
+			sr.Error = stepResult.Err.Error()
+		}
+		result.Steps = append(result.Steps, sr)
+	}
+	return result
+}
 
-		fs := types.FailedScenario{
-			Name:       scenario.Name,
-			FailedStep: step.Text,
-			Line:       fmt.Sprintf("%v:%v", feature.Uri, step.Location.Line),
-			Error:      failure.Err.Error(),
+// Scenarios running at once finish in any order, so put them back in the order
+// they are in the features.
+func (f *xdsFmt) sortScenarios() {
+	position := map[string]int{}
+	for _, feature := range f.Storage.MustGetFeatures() {
+		for _, pickle := range feature.Pickles {
+			position[pickle.Id] = len(position)
 		}
+	}
+	order := make([]int, len(f.pickles))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return position[f.pickles[order[i]]] < position[f.pickles[order[j]]]
+	})
+	scenarios := make([]types.ScenarioResult, len(order))
+	for i, index := range order {
+		scenarios[i] = f.results.Scenarios[index]
+	}
+	f.results.Scenarios = scenarios
+}
 
-		failedScenarios = append(failedScenarios, fs)
+func (f *xdsFmt) gatherFailedScenarios() (failedScenarios []types.FailedScenario) {
+	for _, scenario := range f.results.Scenarios {
+		if scenario.Status != godog.StepFailed.String() {
+			continue
+		}
+		failedScenarios = append(failedScenarios, scenario.Failure())
 	}
 	return failedScenarios
 }
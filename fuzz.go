@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/ii/xds-test-harness/internal/fuzz"
+	"github.com/ii/xds-test-harness/internal/report"
+	"github.com/ii/xds-test-harness/internal/types"
+	"github.com/spf13/pflag"
+)
+
+// The fuzz subcommand sends the target malformed and adversarial discovery
+// requests, and checks it answers them with proper gRPC errors or ignores them,
+// without crashing or upsetting the other streams on the node:
+//
+//	xds-test-harness fuzz --mutations 100 --seed 7
+//
+// It exits with exitFailed when any case found a problem, and exitSetupError
+// when the run couldn't be set up.
+func fuzzCommand(args []string) int {
+	flags := pflag.NewFlagSet("fuzz", pflag.ExitOnError)
+	target := flags.StringP("target", "t", ":18000", "Port of xDS target to test")
+	adapter := flags.StringP("adapter", "a", ":17000", "Port of adapter on target")
+	variants := flags.StringArray("variant", []string{
+		string(types.SotwNonAggregated),
+		string(types.SotwAggregated),
+		string(types.IncrementalNonAggregated),
+		string(types.IncrementalAggregated),
+	}, "xDS variant to fuzz. Can be given more than once; defaults to all of them")
+	service := flags.String("service", "CDS", "Service the requests are for")
+	mutations := flags.Int("mutations", 20, "Number of random mutations of a well-formed request to try, after the fixed cases")
+	seed := flags.Int64("seed", 1, "Seed for the mutations and garbage")
+	node := flags.String("node", "fuzz", "Node ID the requests are sent for")
+	wait := flags.Duration("wait", 2*time.Second, "How long to wait for the target to answer each request")
+	asJSON := flags.Bool("json", false, "print the results as json instead of text")
+	out := flags.String("out", "fuzz.json", "Path to write the results as json to. Set it empty to not write them.")
+	_ = flags.Parse(args)
+
+	results := []types.FuzzResults{}
+	passed := true
+	for _, variant := range *variants {
+		result, err := fuzz.Run(fuzz.Config{
+			Target:    *target,
+			Adapter:   *adapter,
+			Variant:   types.Variant(variant),
+			Service:   *service,
+			Mutations: *mutations,
+			Seed:      *seed,
+			Node:      *node,
+			Wait:      *wait,
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitSetupError
+		}
+		results = append(results, result)
+		passed = passed && result.Passed
+	}
+
+	data, _ := json.MarshalIndent(results, "", "  ")
+	if *out != "" {
+		if err := ioutil.WriteFile(*out, append(data, '\n'), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "cannot write fuzz results: %v\n", err)
+			return exitSetupError
+		}
+	}
+	if *asJSON {
+		fmt.Println(string(data))
+	} else {
+		fmt.Print(report.FuzzText(results))
+	}
+	if !passed {
+		return exitFailed
+	}
+	return exitPassed
+}
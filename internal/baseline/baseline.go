@@ -0,0 +1,169 @@
+// Package baseline compares a run against a list of scenarios that are known to
+// fail, so a run only fails on new failures.
+//
+// A baseline file is yaml, keyed by variant:
+//
+//	sotw non-aggregated:
+//	  - scenario: "[CDS] Client can unsubscribe from some resources"
+//	    reason: our server doesn't support unsubscribing yet
+//	incremental aggregated:
+//	  - scenario: "Subscribing to resources at a set version"
+//	    example: "| RDS | A | 1 |"
+//	    reason: tracked in issue 12
+//
+// Without an example, an entry covers every example of a scenario outline.
+package baseline
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ii/xds-test-harness/internal/types"
+	"github.com/kylelemons/go-gypsy/yaml"
+)
+
+type Entry struct {
+	Variant  string `json:"variant"`
+	Scenario string `json:"scenario"`
+	Example  string `json:"example,omitempty"`
+	Reason   string `json:"reason"`
+}
+
+type NewFailure struct {
+	Variant string `json:"variant"`
+	types.FailedScenario
+}
+
+type Comparison struct {
+	// Baseline entries that failed again, as expected.
+	Known []Entry `json:"known"`
+	// Failures that are not in the baseline. Any of these fail the run.
+	New []NewFailure `json:"new"`
+	// Baseline entries whose scenarios all passed, and can come out of the baseline.
+	NowPassing []Entry `json:"nowPassing"`
+}
+
+func Load(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read baseline: %v", err)
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+func Parse(r io.Reader) ([]Entry, error) {
+	root, err := yaml.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse baseline: %v", err)
+	}
+	if root == nil {
+		return nil, nil
+	}
+	variants, ok := root.(yaml.Map)
+	if !ok {
+		return nil, fmt.Errorf("baseline should be a map of variants to known failures")
+	}
+	names := []string{}
+	for name := range variants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	entries := []Entry{}
+	for _, variant := range names {
+		list, ok := variants[variant].(yaml.List)
+		if !ok {
+			return nil, fmt.Errorf("known failures for %v should be a list", variant)
+		}
+		for i, item := range list {
+			fields, ok := item.(yaml.Map)
+			if !ok {
+				return nil, fmt.Errorf("known failure %v for %v should be a map", i, variant)
+			}
+			entry := Entry{
+				Variant:  variant,
+				Scenario: scalar(fields["scenario"]),
+				Example:  scalar(fields["example"]),
+				Reason:   scalar(fields["reason"]),
+			}
+			if entry.Scenario == "" {
+				return nil, fmt.Errorf("known failure %v for %v has no scenario", i, variant)
+			}
+			if entry.Reason == "" {
+				return nil, fmt.Errorf("known failure %q for %v has no reason", entry.Scenario, variant)
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// Checks every scenario that ran against the baseline. A baseline entry for a
+// scenario that didn't run, from a skipped variant or tag, is neither known nor
+// now passing.
+func Compare(entries []Entry, results types.Results) (comparison Comparison) {
+	failed := make([]bool, len(entries))
+	passed := make([]bool, len(entries))
+	for _, variant := range results.ResultsByVariant {
+		for _, scenario := range variant.Scenarios {
+			i := match(entries, variant.Name, scenario)
+			switch {
+			case types.Failing(scenario.Status) && i < 0:
+				failure := scenario.Failure()
+				if failure.Error == "" {
+					failure.Error = "scenario has " + scenario.Status + " steps"
+				}
+				comparison.New = append(comparison.New, NewFailure{
+					Variant:        variant.Name,
+					FailedScenario: failure,
+				})
+			case types.Failing(scenario.Status):
+				failed[i] = true
+			case scenario.Status == "passed" && i >= 0:
+				passed[i] = true
+			}
+		}
+	}
+	for i, entry := range entries {
+		if failed[i] {
+			comparison.Known = append(comparison.Known, entry)
+		} else if passed[i] {
+			comparison.NowPassing = append(comparison.NowPassing, entry)
+		}
+	}
+	return comparison
+}
+
+func match(entries []Entry, variant string, scenario types.ScenarioResult) int {
+	for i, entry := range entries {
+		if entry.Variant != variant || entry.Scenario != scenario.Name {
+			continue
+		}
+		if entry.Example == "" || normalise(entry.Example) == normalise(scenario.Example) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Example rows are compared cell by cell, so the spacing in the baseline doesn't
+// have to match the feature file.
+func normalise(example string) string {
+	cells := strings.Split(strings.Trim(example, " |"), "|")
+	for i, cell := range cells {
+		cells[i] = strings.TrimSpace(cell)
+	}
+	return strings.Join(cells, "|")
+}
+
+func scalar(n yaml.Node) string {
+	s, ok := n.(yaml.Scalar)
+	if !ok {
+		return ""
+	}
+	return strings.Trim(strings.TrimSpace(s.String()), `"'`)
+}
@@ -0,0 +1,89 @@
+package baseline
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ii/xds-test-harness/internal/types"
+)
+
+var testBaseline = `
+sotw non-aggregated:
+  - scenario: "[CDS] Unsubscribe"
+    reason: not supported yet
+  - scenario: "[RDS] Versions"
+    example: "|RDS| A |1|"
+    reason: tracked upstream
+incremental aggregated:
+  - scenario: "[LDS] Subscribe"
+    reason: fixed since
+`
+
+func TestParse(t *testing.T) {
+	entries, err := Parse(strings.NewReader(testBaseline))
+	if err != nil {
+		t.Fatalf("Error parsing baseline when expecting no err: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %v: %v", len(entries), entries)
+	}
+	rds := entries[2]
+	if rds.Variant != "sotw non-aggregated" || rds.Scenario != "[RDS] Versions" || rds.Example != "|RDS| A |1|" || rds.Reason != "tracked upstream" {
+		t.Errorf("Entry not parsed as expected: %+v", rds)
+	}
+
+	_, err = Parse(strings.NewReader("sotw aggregated:\n  - scenario: \"[CDS] Wildcard\"\n"))
+	if err == nil {
+		t.Errorf("Expected an error for an entry without a reason")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	entries, err := Parse(strings.NewReader(testBaseline))
+	if err != nil {
+		t.Fatalf("Error parsing baseline when expecting no err: %v", err)
+	}
+	results := types.Results{
+		ResultsByVariant: []types.VariantResults{
+			{
+				Name: "sotw non-aggregated",
+				Scenarios: []types.ScenarioResult{
+					{Name: "[CDS] Unsubscribe", Status: "failed"},
+					{Name: "[RDS] Versions", Example: "| RDS | A | 1 |", Status: "passed"},
+					{Name: "[RDS] Versions", Example: "| RDS | B | 2 |", Status: "failed", FailedStep: "the Client receives", Error: "wrong version"},
+				},
+			},
+			{
+				Name: "incremental aggregated",
+				Scenarios: []types.ScenarioResult{
+					{Name: "[LDS] Subscribe", Status: "passed"},
+				},
+			},
+		},
+	}
+
+	comparison := Compare(entries, results)
+	if len(comparison.Known) != 1 || comparison.Known[0].Scenario != "[CDS] Unsubscribe" {
+		t.Errorf("Known failures not as expected: %v", comparison.Known)
+	}
+	if len(comparison.New) != 1 || comparison.New[0].Example != "| RDS | B | 2 |" || comparison.New[0].Variant != "sotw non-aggregated" {
+		t.Errorf("New failures not as expected: %v", comparison.New)
+	}
+	if len(comparison.NowPassing) != 2 {
+		t.Errorf("Expected the RDS example and LDS scenario to be now passing, got: %v", comparison.NowPassing)
+	}
+
+	comparison = Compare(nil, results)
+	if len(comparison.New) != 2 {
+		t.Errorf("Without a baseline every failure should be new, got: %v", comparison.New)
+	}
+
+	results.ResultsByVariant[1].Scenarios = append(results.ResultsByVariant[1].Scenarios,
+		types.ScenarioResult{Name: "[LDS] Typo", Status: "undefined"},
+		types.ScenarioResult{Name: "[LDS] Restart", Status: "pending"},
+	)
+	comparison = Compare(entries, results)
+	if len(comparison.New) != 2 || comparison.New[1].Error != "scenario has undefined steps" {
+		t.Errorf("Undefined scenarios should be new failures, and pending ones not, got: %v", comparison.New)
+	}
+}
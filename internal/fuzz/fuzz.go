@@ -0,0 +1,154 @@
+// Package fuzz sends a target malformed and adversarial discovery requests, the
+// kind the runner's well-formed ones never are, and checks it answers them with
+// proper gRPC errors or ignores them, without crashing or upsetting the other
+// streams on the node.
+package fuzz
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/ii/xds-test-harness/internal/parser"
+	"github.com/ii/xds-test-harness/internal/runner"
+	"github.com/ii/xds-test-harness/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// The resource every stream subscribes to.
+const resource = "A"
+
+type Config struct {
+	Target  string
+	Adapter string
+	Variant types.Variant
+	Service string
+	// Random mutations of a well-formed request to try, after the fixed cases.
+	Mutations int
+	Seed      int64
+	Node      string
+	// How long to wait for the target to answer each request.
+	Wait time.Duration
+}
+
+func Run(c Config) (results types.FuzzResults, err error) {
+	suite := runner.NewSuite(c.Variant, false)
+	if suite == nil {
+		return results, fmt.Errorf("unknown variant: %v", c.Variant)
+	}
+	typeURL, err := parser.ServiceToTypeURL(c.Service)
+	if err != nil {
+		return results, err
+	}
+	otherTypeURL := parser.TypeUrlLDS
+	if typeURL == parser.TypeUrlLDS {
+		otherTypeURL = parser.TypeUrlCDS
+	}
+	results = types.FuzzResults{Variant: string(c.Variant), Service: c.Service, Seed: c.Seed, Passed: true}
+
+	base := runner.FreshRunner()
+	base.NodeID = c.Node
+	if err = base.ConnectClient("adapter", c.Adapter); err != nil {
+		return results, fmt.Errorf("cannot connect to adapter: %v", err)
+	}
+	defer base.ClearState()
+	if err = base.ConnectClient("target", c.Target); err != nil {
+		return results, fmt.Errorf("cannot connect to target: %v", err)
+	}
+	base.Aggregated = suite.Aggregated
+	base.Incremental = suite.Incremental
+	// the malformed requests go over a connection of their own, so one the target
+	// drops takes none of the other streams with it.
+	attacker := runner.FreshRunner()
+	if err = attacker.ConnectClient("target", c.Target); err != nil {
+		return results, fmt.Errorf("cannot connect to target: %v", err)
+	}
+	defer attacker.Target.Conn.Close()
+
+	version := 1
+	if err = base.TargetSetupWithServiceResourcesAndVersion(c.Service, resource, strconv.Itoa(version)); err != nil {
+		return results, err
+	}
+	update := func() error {
+		version++
+		return base.ResourceOfServiceIsUpdatedToVersion(resource, c.Service, strconv.Itoa(version))
+	}
+	e := env{
+		valid: func() *discovery.DiscoveryRequest {
+			return &discovery.DiscoveryRequest{
+				Node:          &core.Node{Id: c.Node},
+				TypeUrl:       typeURL,
+				ResourceNames: []string{resource},
+			}
+		},
+		update:       update,
+		random:       rand.New(rand.NewSource(c.Seed)),
+		otherTypeURL: otherTypeURL,
+	}
+
+	cases := []abuse{}
+	for _, a := range abuses {
+		if !a.aggregatedOnly || suite.Aggregated {
+			cases = append(cases, a)
+		}
+	}
+	for i := 1; i <= c.Mutations; i++ {
+		cases = append(cases, mutation(i))
+	}
+
+	for _, a := range cases {
+		result := types.FuzzCase{Name: a.name}
+		// a well-behaved client on the same node, which should be none the wiser.
+		bystander := runner.FreshRunner(base)
+		if err := bystander.ClientSubscribesToServiceForResources(c.Service, []string{resource}); err != nil {
+			return results, fmt.Errorf("cannot subscribe before %v: %v", a.name, err)
+		}
+
+		s, err := open(attacker.Target.Conn, c.Service, suite.Aggregated, suite.Incremental, c.Wait)
+		if err != nil {
+			result.Problems = append(result.Problems, fmt.Sprintf("cannot open stream: %v", err))
+		} else {
+			// a target closing the stream ends the sending too, and the outcome says why.
+			if err := a.run(s, e); err != nil && err != io.EOF {
+				result.Problems = append(result.Problems, err.Error())
+			}
+			outcome, problem := s.outcome()
+			result.Outcome = outcome
+			if problem != nil {
+				result.Problems = append(result.Problems, problem.Error())
+			}
+		}
+
+		down := false
+		if err := update(); err != nil {
+			result.Problems = append(result.Problems, fmt.Sprintf("adapter: %v", err))
+		} else if err := bystander.ClientHasResourceOfServiceAtVersionOrLater(resource, c.Service, strconv.Itoa(version)); err != nil {
+			result.Problems = append(result.Problems, fmt.Sprintf("another stream on the node stopped getting updates: %v", err))
+		}
+		bystander.CloseStream()
+		probe := runner.FreshRunner(base)
+		if err := probe.ClientSubscribesToServiceForResources(c.Service, []string{resource}); err != nil {
+			down = true
+		} else if err := probe.ClientHasResourceOfServiceAtVersionOrLater(resource, c.Service, strconv.Itoa(version)); err != nil {
+			down = true
+		}
+		probe.CloseStream()
+		if down {
+			result.Problems = append(result.Problems, "the target stopped answering new streams")
+		}
+
+		result.Passed = len(result.Problems) == 0
+		results.Passed = results.Passed && result.Passed
+		results.Cases = append(results.Cases, result)
+		log.Info().Msgf("[%v] %v: %v", c.Variant, a.name, result.Outcome)
+		if down {
+			log.Error().Msgf("Target stopped answering after %v, not trying the rest", a.name)
+			break
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,174 @@
+package fuzz
+
+import (
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/ii/xds-test-harness/internal/parser"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func valid() *discovery.DiscoveryRequest {
+	return &discovery.DiscoveryRequest{
+		Node:          &core.Node{Id: "fuzz"},
+		TypeUrl:       parser.TypeUrlCDS,
+		ResourceNames: []string{"A"},
+	}
+}
+
+func TestMutate(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		request := mutate(random, valid(), parser.TypeUrlLDS)
+		if proto.Equal(request, valid()) {
+			t.Fatalf("Mutation %v left the request as it was", i)
+		}
+		// whatever the mutation, the request has to be sendable.
+		if _, err := proto.Marshal(request); err != nil {
+			t.Fatalf("Mutation %v cannot be marshalled: %v", i, err)
+		}
+		if _, err := proto.Marshal(toDelta(request)); err != nil {
+			t.Fatalf("Mutation %v cannot be marshalled as a delta request: %v", i, err)
+		}
+	}
+
+	first := mutate(rand.New(rand.NewSource(7)), valid(), parser.TypeUrlLDS)
+	second := mutate(rand.New(rand.NewSource(7)), valid(), parser.TypeUrlLDS)
+	if !proto.Equal(first, second) {
+		t.Errorf("Mutations differ with the same seed")
+	}
+}
+
+func TestGarbage(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		g := garbage(random, 64)
+		if len(g) > 64 || !utf8.ValidString(g) {
+			t.Fatalf("Garbage not as expected: %q", g)
+		}
+	}
+}
+
+func ended(err error) *stream {
+	s := &stream{
+		cancel:    func() {},
+		wait:      50 * time.Millisecond,
+		responses: make(chan response, 16),
+		ended:     make(chan error, 1),
+	}
+	if err != nil {
+		s.ended <- err
+	}
+	return s
+}
+
+func TestOutcome(t *testing.T) {
+	cases := []struct {
+		err     error
+		outcome string
+		problem bool
+	}{
+		{status.Error(codes.InvalidArgument, "unknown type"), "closed with InvalidArgument: unknown type", false},
+		{status.Error(codes.Unknown, "oops"), "closed with Unknown: oops", true},
+		{status.Error(codes.Internal, "panic"), "closed with Internal: panic", true},
+		{io.EOF, "closed", false},
+		{nil, "ignored", false},
+	}
+	for _, c := range cases {
+		outcome, problem := ended(c.err).outcome()
+		if outcome != c.outcome || (problem != nil) != c.problem {
+			t.Errorf("Wrong outcome for %v. expected: %v (problem: %v), actual: %v (%v)", c.err, c.outcome, c.problem, outcome, problem)
+		}
+	}
+
+	s := ended(nil)
+	s.responses <- response{"1", "1"}
+	s.responses <- response{"2", "2"}
+	if outcome, _ := s.outcome(); outcome != "answered with 2 responses" {
+		t.Errorf("Wrong outcome for answered requests: %v", outcome)
+	}
+}
+
+func TestAbuses(t *testing.T) {
+	names := map[string]bool{}
+	for _, a := range abuses {
+		names[a.name] = true
+	}
+	for _, expected := range []string{"unknown type url", "garbage nonce", "stale nonce", "huge resource list", "mismatched type url", "missing node"} {
+		if !names[expected] {
+			t.Errorf("No case for %v", expected)
+		}
+	}
+}
+
+// Sends whatever the fuzzer comes up with to a running target, and checks it
+// neither answers with an improper error nor stops answering. Only runs when
+// XDS_FUZZ_TARGET is set to the target's address:
+//
+//	XDS_FUZZ_TARGET=:18000 go test ./internal/fuzz -fuzz FuzzDiscoveryRequest
+func FuzzDiscoveryRequest(f *testing.F) {
+	address := os.Getenv("XDS_FUZZ_TARGET")
+	f.Add(parser.TypeUrlCDS, "", "A", "", "fuzz")
+	f.Add(unknownTypeURL, "1", "A,B", "nonce", "")
+	f.Add("", "garbage", "*", "1", "fuzz")
+	f.Fuzz(func(t *testing.T, typeURL, version, names, nonce, node string) {
+		if address == "" {
+			t.Skip("XDS_FUZZ_TARGET not set")
+		}
+		for _, s := range []string{typeURL, version, names, nonce, node} {
+			if !utf8.ValidString(s) {
+				t.Skip("protobuf strings have to be utf-8")
+			}
+		}
+		conn, err := grpc.Dial(address, grpc.WithInsecure())
+		if err != nil {
+			t.Fatalf("cannot connect to target: %v", err)
+		}
+		defer conn.Close()
+
+		request := &discovery.DiscoveryRequest{
+			VersionInfo:   version,
+			TypeUrl:       typeURL,
+			ResourceNames: strings.Split(names, ","),
+			ResponseNonce: nonce,
+		}
+		if node != "" {
+			request.Node = &core.Node{Id: node}
+		}
+		s, err := open(conn, "ADS", true, false, 200*time.Millisecond)
+		if err != nil {
+			t.Fatalf("cannot open stream: %v", err)
+		}
+		if err := s.send(request); err != nil && err != io.EOF {
+			t.Fatalf("cannot send request: %v", err)
+		}
+		if _, problem := s.outcome(); problem != nil {
+			t.Errorf("%v for %v", problem, request)
+		}
+
+		// the target has to keep taking well-formed requests after it.
+		s, err = open(conn, "ADS", true, false, 5*time.Second)
+		if err != nil {
+			t.Fatalf("target stopped opening streams: %v", err)
+		}
+		defer s.cancel()
+		if err := s.send(valid()); err != nil {
+			t.Fatalf("target stopped taking requests: %v", err)
+		}
+		select {
+		case err := <-s.ended:
+			t.Fatalf("target ended a well-formed stream: %v", err)
+		case <-time.After(200 * time.Millisecond):
+		}
+	})
+}
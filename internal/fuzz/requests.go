@@ -0,0 +1,178 @@
+package fuzz
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	rpc "google.golang.org/genproto/googleapis/rpc/status"
+)
+
+const (
+	unknownTypeURL = "type.googleapis.com/xds.fuzz.v3.Unknown"
+	// Names in the huge resource list, well under gRPC's default message limit of
+	// 4MB, so it's the server's handling of them that's tried, not the limit.
+	hugeResourceList = 100000
+)
+
+// What a case has to work with: a well-formed request to start from, and a way
+// to have the target send the stream another response.
+type env struct {
+	valid        func() *discovery.DiscoveryRequest
+	update       func() error
+	random       *rand.Rand
+	otherTypeURL string
+}
+
+// A malformed or adversarial exchange, tried on a stream of its own.
+type abuse struct {
+	name string
+	// Only an aggregated stream can carry more than one type.
+	aggregatedOnly bool
+	run            func(s *stream, e env) error
+}
+
+var abuses = []abuse{
+	{name: "unknown type url", run: func(s *stream, e env) error {
+		request := e.valid()
+		request.TypeUrl = unknownTypeURL
+		return s.send(request)
+	}},
+	{name: "garbage nonce", run: func(s *stream, e env) error {
+		if _, err := s.exchange(e.valid()); err != nil {
+			return err
+		}
+		request := e.valid()
+		request.Node = nil
+		request.VersionInfo = garbage(e.random, 64)
+		request.ResponseNonce = garbage(e.random, 1024)
+		return s.send(request)
+	}},
+	{name: "stale nonce", run: func(s *stream, e env) error {
+		first, err := s.exchange(e.valid())
+		if err != nil {
+			return err
+		}
+		if err := s.send(ack(e.valid(), first)); err != nil {
+			return err
+		}
+		if err := e.update(); err != nil {
+			return err
+		}
+		if _, err := s.response(); err != nil {
+			return fmt.Errorf("no response to an update: %v", err)
+		}
+		// acks the first response again, long after the second replaced it.
+		return s.send(ack(e.valid(), first))
+	}},
+	{name: "huge resource list", run: func(s *stream, e env) error {
+		request := e.valid()
+		for i := 0; i < hugeResourceList; i++ {
+			request.ResourceNames = append(request.ResourceNames, fmt.Sprintf("fuzz-%v", i))
+		}
+		return s.send(request)
+	}},
+	{name: "mismatched type url", aggregatedOnly: true, run: func(s *stream, e env) error {
+		first, err := s.exchange(e.valid())
+		if err != nil {
+			return err
+		}
+		request := ack(e.valid(), first)
+		request.TypeUrl = e.otherTypeURL
+		return s.send(request)
+	}},
+	{name: "missing node", run: func(s *stream, e env) error {
+		request := e.valid()
+		request.Node = nil
+		return s.send(request)
+	}},
+}
+
+// The case for the nth random mutation of a well-formed request, sent as the
+// first request on the stream and again as a follow-up.
+func mutation(n int) abuse {
+	return abuse{name: fmt.Sprintf("mutation %v", n), run: func(s *stream, e env) error {
+		if err := s.send(mutate(e.random, e.valid(), e.otherTypeURL)); err != nil {
+			return err
+		}
+		return s.send(mutate(e.random, e.valid(), e.otherTypeURL))
+	}}
+}
+
+func ack(request *discovery.DiscoveryRequest, to response) *discovery.DiscoveryRequest {
+	request.Node = nil
+	request.VersionInfo = to.version
+	request.ResponseNonce = to.nonce
+	return request
+}
+
+// Changes at least one field of the request to something a server wouldn't
+// expect.
+func mutate(random *rand.Rand, request *discovery.DiscoveryRequest, otherTypeURL string) *discovery.DiscoveryRequest {
+	mutations := []func(){
+		func() {
+			request.TypeUrl = []string{"", unknownTypeURL, otherTypeURL, request.TypeUrl + "/", garbage(random, 128)}[random.Intn(5)]
+		},
+		func() { request.VersionInfo = garbage(random, 128) },
+		func() { request.ResponseNonce = garbage(random, 1024) },
+		func() {
+			switch random.Intn(4) {
+			case 0:
+				request.ResourceNames = []string{""}
+			case 1:
+				for i := 0; i < 100; i++ {
+					request.ResourceNames = append(request.ResourceNames, request.ResourceNames[0])
+				}
+			case 2:
+				request.ResourceNames = append(request.ResourceNames, "*")
+			default:
+				request.ResourceNames = []string{garbage(random, 256), garbage(random, 256)}
+			}
+		},
+		func() {
+			switch random.Intn(3) {
+			case 0:
+				request.Node = nil
+			case 1:
+				request.Node = &core.Node{}
+			default:
+				request.Node = &core.Node{Id: garbage(random, 64*1024), Cluster: garbage(random, 64)}
+			}
+		},
+		func() {
+			request.ErrorDetail = &rpc.Status{Code: random.Int31n(20), Message: garbage(random, 256)}
+		},
+	}
+	mutated := false
+	for !mutated {
+		for _, mutate := range mutations {
+			if random.Intn(3) == 0 {
+				mutate()
+				mutated = true
+			}
+		}
+	}
+	return request
+}
+
+// A random string of printable ascii, as protobuf strings have to be utf-8.
+func garbage(random *rand.Rand, max int) string {
+	var b strings.Builder
+	n := random.Intn(max + 1)
+	for i := 0; i < n; i++ {
+		b.WriteByte(byte(' ' + random.Intn('~'-' '+1)))
+	}
+	return b.String()
+}
+
+func toDelta(request *discovery.DiscoveryRequest) *discovery.DeltaDiscoveryRequest {
+	return &discovery.DeltaDiscoveryRequest{
+		Node:                   request.Node,
+		TypeUrl:                request.TypeUrl,
+		ResourceNamesSubscribe: request.ResourceNames,
+		ResponseNonce:          request.ResponseNonce,
+		ErrorDetail:            request.ErrorDetail,
+	}
+}
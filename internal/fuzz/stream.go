@@ -0,0 +1,174 @@
+package fuzz
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	cds "github.com/envoyproxy/go-control-plane/envoy/service/cluster/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	eds "github.com/envoyproxy/go-control-plane/envoy/service/endpoint/v3"
+	lds "github.com/envoyproxy/go-control-plane/envoy/service/listener/v3"
+	rds "github.com/envoyproxy/go-control-plane/envoy/service/route/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The parts of a response the cases need.
+type response struct {
+	version string
+	nonce   string
+}
+
+// A stream of the harness's own, rather than a runner's, so any request at all
+// can be sent on it and how it ends is kept.
+type stream struct {
+	raw    grpc.ClientStream
+	delta  bool
+	cancel context.CancelFunc
+	// How long to wait for the target to answer.
+	wait      time.Duration
+	responses chan response
+	// The error the stream ended with, or io.EOF.
+	ended chan error
+}
+
+func open(conn *grpc.ClientConn, service string, aggregated, incremental bool, wait time.Duration) (*stream, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var (
+		s   grpc.ClientStream
+		err error
+	)
+	if aggregated {
+		service = "ADS"
+	}
+	switch {
+	case service == "ADS" && incremental:
+		s, err = discovery.NewAggregatedDiscoveryServiceClient(conn).DeltaAggregatedResources(ctx)
+	case service == "ADS":
+		s, err = discovery.NewAggregatedDiscoveryServiceClient(conn).StreamAggregatedResources(ctx)
+	case service == "LDS" && incremental:
+		s, err = lds.NewListenerDiscoveryServiceClient(conn).DeltaListeners(ctx)
+	case service == "LDS":
+		s, err = lds.NewListenerDiscoveryServiceClient(conn).StreamListeners(ctx)
+	case service == "CDS" && incremental:
+		s, err = cds.NewClusterDiscoveryServiceClient(conn).DeltaClusters(ctx)
+	case service == "CDS":
+		s, err = cds.NewClusterDiscoveryServiceClient(conn).StreamClusters(ctx)
+	case service == "RDS" && incremental:
+		s, err = rds.NewRouteDiscoveryServiceClient(conn).DeltaRoutes(ctx)
+	case service == "RDS":
+		s, err = rds.NewRouteDiscoveryServiceClient(conn).StreamRoutes(ctx)
+	case service == "EDS" && incremental:
+		s, err = eds.NewEndpointDiscoveryServiceClient(conn).DeltaEndpoints(ctx)
+	case service == "EDS":
+		s, err = eds.NewEndpointDiscoveryServiceClient(conn).StreamEndpoints(ctx)
+	default:
+		err = fmt.Errorf("no stream for service: %v", service)
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	st := &stream{
+		raw:       s,
+		delta:     incremental,
+		cancel:    cancel,
+		wait:      wait,
+		responses: make(chan response, 16),
+		ended:     make(chan error, 1),
+	}
+	go st.receive()
+	return st, nil
+}
+
+func (s *stream) receive() {
+	for {
+		var r response
+		if s.delta {
+			in := &discovery.DeltaDiscoveryResponse{}
+			if err := s.raw.RecvMsg(in); err != nil {
+				s.ended <- err
+				return
+			}
+			r = response{in.SystemVersionInfo, in.Nonce}
+		} else {
+			in := &discovery.DiscoveryResponse{}
+			if err := s.raw.RecvMsg(in); err != nil {
+				s.ended <- err
+				return
+			}
+			r = response{in.VersionInfo, in.Nonce}
+		}
+		select {
+		case s.responses <- r:
+		default:
+			// nothing waits on the responses after the case is done.
+		}
+	}
+}
+
+func (s *stream) send(request *discovery.DiscoveryRequest) error {
+	if s.delta {
+		return s.raw.SendMsg(toDelta(request))
+	}
+	return s.raw.SendMsg(request)
+}
+
+// Waits for the next response, or for the stream to end.
+func (s *stream) response() (response, error) {
+	select {
+	case r := <-s.responses:
+		return r, nil
+	case err := <-s.ended:
+		s.ended <- err
+		return response{}, fmt.Errorf("stream ended: %v", err)
+	case <-time.After(s.wait):
+		return response{}, fmt.Errorf("nothing within %v", s.wait)
+	}
+}
+
+// Sends a well-formed request, and waits for the target to answer it.
+func (s *stream) exchange(request *discovery.DiscoveryRequest) (response, error) {
+	if err := s.send(request); err != nil {
+		return response{}, err
+	}
+	r, err := s.response()
+	if err != nil {
+		return r, fmt.Errorf("no response to a well-formed request: %v", err)
+	}
+	return r, nil
+}
+
+// Waits to see what the target makes of the requests sent, then closes the
+// stream. A target may end the stream with an error, or ignore or answer what
+// it was sent, but an Unknown or Internal error isn't a proper answer to a
+// request it can tell is wrong.
+func (s *stream) outcome() (outcome string, problem error) {
+	defer s.cancel()
+	answered := 0
+	timeout := time.After(s.wait)
+	for {
+		select {
+		case <-s.responses:
+			answered++
+		case err := <-s.ended:
+			if err == io.EOF {
+				return "closed", nil
+			}
+			st := status.Convert(err)
+			outcome = fmt.Sprintf("closed with %v: %v", st.Code(), st.Message())
+			if st.Code() == codes.Unknown || st.Code() == codes.Internal {
+				problem = fmt.Errorf("ended the stream with %v, not a gRPC error saying what was wrong", st.Code())
+			}
+			return outcome, problem
+		case <-timeout:
+			if answered > 0 {
+				return fmt.Sprintf("answered with %v responses", answered), nil
+			}
+			return "ignored", nil
+		}
+	}
+}
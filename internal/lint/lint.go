@@ -0,0 +1,217 @@
+// Package lint checks feature files for mistakes that would otherwise only show
+// up in a run against a live server: steps that match no step definition,
+// scenarios whose tags put them in no variant, and unknown service names.
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cucumber/godog"
+	messages "github.com/cucumber/messages-go/v16"
+	"github.com/ii/xds-test-harness/internal/parser"
+	"github.com/ii/xds-test-harness/internal/runner"
+	"github.com/ii/xds-test-harness/internal/types"
+)
+
+var variants = []types.Variant{
+	types.SotwNonAggregated,
+	types.SotwAggregated,
+	types.IncrementalNonAggregated,
+	types.IncrementalAggregated,
+}
+
+type Problem struct {
+	Feature string `json:"feature"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%v:%v: %v", p.Feature, p.Line, p.Message)
+}
+
+// Collects the patterns of the steps the runner registers.
+type stepPatterns []*regexp.Regexp
+
+func (s *stepPatterns) Step(expr, stepFunc interface{}) {
+	switch e := expr.(type) {
+	case string:
+		*s = append(*s, regexp.MustCompile(e))
+	case *regexp.Regexp:
+		*s = append(*s, e)
+	}
+}
+
+func (s stepPatterns) match(text string) bool {
+	for _, pattern := range s {
+		if pattern.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// Lints every feature in dir, giving its problems sorted by feature and line.
+func Lint(dir string) ([]Problem, error) {
+	all, err := retrieve(dir, "")
+	if err != nil {
+		return nil, err
+	}
+	patterns := &stepPatterns{}
+	(&runner.Runner{}).LoadSteps(patterns)
+
+	inVariant := map[string]bool{}
+	for _, variant := range variants {
+		suite := runner.NewSuite(variant, false)
+		if err := suite.SetTags(""); err != nil {
+			return nil, err
+		}
+		features, err := retrieve(dir, suite.Tags)
+		if err != nil {
+			return nil, err
+		}
+		for _, feature := range features {
+			for _, pickle := range feature.Pickles {
+				inVariant[pickleKey(pickle)] = true
+			}
+		}
+	}
+
+	problems := map[Problem]bool{}
+	for _, feature := range all {
+		uri := runner.FeatureURI(dir, feature.Uri)
+		for _, pickle := range feature.Pickles {
+			if !inVariant[pickleKey(pickle)] {
+				line := 0
+				if scenario := feature.FindScenario(pickle.AstNodeIds[0]); scenario != nil {
+					line = int(scenario.Location.Line)
+				}
+				problems[Problem{uri, line, fmt.Sprintf("scenario %q is not tagged to run in any variant", pickle.Name)}] = true
+			}
+			for _, step := range pickle.Steps {
+				astStep := feature.FindStep(step.AstNodeIds[0])
+				if !patterns.match(step.Text) {
+					problems[Problem{uri, int(astStep.Location.Line), fmt.Sprintf("step %q matches no step definition", astStep.Text)}] = true
+				}
+				if step.Argument != nil && step.Argument.DataTable != nil {
+					for _, p := range dataTableServices(step.Argument.DataTable) {
+						problems[Problem{uri, int(astStep.Location.Line), p}] = true
+					}
+				}
+			}
+		}
+		for _, child := range feature.GherkinDocument.Feature.Children {
+			if child.Scenario == nil {
+				continue
+			}
+			for _, examples := range child.Scenario.Examples {
+				for _, p := range exampleServices(uri, examples) {
+					problems[p] = true
+				}
+			}
+		}
+	}
+
+	sorted := []Problem{}
+	for p := range problems {
+		sorted = append(sorted, p)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Feature != sorted[j].Feature {
+			return sorted[i].Feature < sorted[j].Feature
+		}
+		if sorted[i].Line != sorted[j].Line {
+			return sorted[i].Line < sorted[j].Line
+		}
+		return sorted[i].Message < sorted[j].Message
+	})
+	return sorted, nil
+}
+
+// godog's features parsed from dir, with only the scenarios matching tags.
+func retrieve(dir, tags string) ([]feature, error) {
+	suite := godog.TestSuite{Options: &godog.Options{Tags: tags, Paths: []string{dir}}}
+	features, err := suite.RetrieveFeatures()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read features: %v", err)
+	}
+	result := []feature{}
+	for _, f := range features {
+		result = append(result, feature{f.GherkinDocument, f.Pickles, f})
+	}
+	return result, nil
+}
+
+// The parts of godog's feature model we lint, as its own type can't be named here.
+type feature struct {
+	*messages.GherkinDocument
+	Pickles []*messages.Pickle
+	finder
+}
+
+type finder interface {
+	FindScenario(astScenarioID string) *messages.Scenario
+	FindStep(astStepID string) *messages.Step
+}
+
+// Pickle ids change each time features are parsed, but the ids of the scenario and
+// example row they come from don't.
+func pickleKey(pickle *messages.Pickle) string {
+	return pickle.Uri + ":" + strings.Join(pickle.AstNodeIds, ",")
+}
+
+// Columns holding service names, like xDS, xds2, service and services.
+func isServiceColumn(header string) bool {
+	header = strings.ToLower(header)
+	return strings.HasPrefix(header, "xds") || strings.HasPrefix(header, "service")
+}
+
+func unknownServices(value string) (unknown []string) {
+	value = strings.Trim(strings.TrimSpace(value), `"`)
+	if value == "" || strings.HasPrefix(value, "<") {
+		return nil
+	}
+	for _, service := range strings.Split(value, ",") {
+		if _, err := parser.ServiceToTypeURL(strings.TrimSpace(service)); err != nil {
+			unknown = append(unknown, strings.TrimSpace(service))
+		}
+	}
+	return unknown
+}
+
+func exampleServices(uri string, examples *messages.Examples) (problems []Problem) {
+	if examples.TableHeader == nil {
+		return nil
+	}
+	for i, header := range examples.TableHeader.Cells {
+		if !isServiceColumn(header.Value) {
+			continue
+		}
+		for _, row := range examples.TableBody {
+			for _, service := range unknownServices(row.Cells[i].Value) {
+				problems = append(problems, Problem{uri, int(row.Location.Line), fmt.Sprintf("unknown service %q in column %v", service, header.Value)})
+			}
+		}
+	}
+	return problems
+}
+
+func dataTableServices(table *messages.PickleTable) (problems []string) {
+	if len(table.Rows) < 2 {
+		return nil
+	}
+	for i, header := range table.Rows[0].Cells {
+		if !isServiceColumn(header.Value) {
+			continue
+		}
+		for _, row := range table.Rows[1:] {
+			for _, service := range unknownServices(row.Cells[i].Value) {
+				problems = append(problems, fmt.Sprintf("unknown service %q in column %v", service, header.Value))
+			}
+		}
+	}
+	return problems
+}
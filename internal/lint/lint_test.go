@@ -0,0 +1,53 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testFeature = `Feature: Linting
+
+  @sotw @non-aggregated
+  Scenario Outline: [<xDS>] Tagged for a variant
+    Given a target setup with service <xDS>, resources <resources>, and starting version "1"
+    When the Client does a wildcard subscripton to <xDS>
+
+    Examples:
+      | xDS   | resources |
+      | "CDS" | "A"       |
+      | "XDS" | "A"       |
+
+  @sotw @incremetal
+  Scenario: Tagged for no variant
+    Given a target setup with the following state:
+      | service | resources | version |
+      | "LDS"   | "A"       | "1"     |
+      | "NDS"   | "B"       | "1"     |
+`
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "lint.feature"), []byte(testFeature), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := Lint(dir)
+	if err != nil {
+		t.Fatalf("Error linting when expecting no err: %v", err)
+	}
+	actual := []string{}
+	for _, p := range problems {
+		actual = append(actual, p.String())
+	}
+	expected := []string{
+		`features/lint.feature:6: step "the Client does a wildcard subscripton to <xDS>" matches no step definition`,
+		`features/lint.feature:11: unknown service "XDS" in column xDS`,
+		`features/lint.feature:14: scenario "Tagged for no variant" is not tagged to run in any variant`,
+		`features/lint.feature:15: unknown service "NDS" in column service`,
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Problems not as expected.\nexpected:\n%v\nactual:\n%v", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+}
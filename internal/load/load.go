@@ -0,0 +1,274 @@
+// Package load puts a target under the load of many simulated xDS clients, while
+// churning their resources through the adapter, and measures how long updates
+// take to reach the clients.
+package load
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ii/xds-test-harness/internal/parser"
+	"github.com/ii/xds-test-harness/internal/runner"
+	"github.com/ii/xds-test-harness/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Keeps at most this many distinct errors in the results.
+const maxErrors = 10
+
+type Config struct {
+	Target  string
+	Adapter string
+	Variant types.Variant
+	Service string
+	// Clients are spread evenly across the nodes, and their streams across the
+	// connections to the target.
+	Clients     int
+	Nodes       int
+	NodePrefix  string
+	Connections int
+	// Resources set for each node.
+	Resources int
+	// What each client subscribes to: wildcard, all of the resources by name, or a
+	// number of them chosen at random.
+	Subscription string
+	Duration     time.Duration
+	// How often a resource is updated on every node.
+	UpdateInterval time.Duration
+	// How long to wait for the last updates to arrive before stopping.
+	Grace time.Duration
+	// When given, the resident memory of this process is sampled through the run.
+	ServerPID int
+	Seed      int64
+}
+
+func (c Config) node(i int) string {
+	return fmt.Sprintf("%v-%v", c.NodePrefix, i)
+}
+
+func (c Config) resource(i int) string {
+	return fmt.Sprintf("resource-%v", i)
+}
+
+func (c Config) validate() error {
+	if c.Clients < 1 || c.Nodes < 1 || c.Connections < 1 || c.Resources < 1 {
+		return fmt.Errorf("clients, nodes, connections and resources should all be at least 1")
+	}
+	if c.UpdateInterval <= 0 || c.Duration <= 0 {
+		return fmt.Errorf("duration and update interval should be more than 0")
+	}
+	if _, err := subscriptionSize(c.Subscription, c.Resources); err != nil {
+		return err
+	}
+	return nil
+}
+
+// The number of resources a client subscribes to by name, or -1 for a wildcard
+// subscription.
+func subscriptionSize(subscription string, resources int) (int, error) {
+	switch subscription {
+	case "wildcard":
+		return -1, nil
+	case "all":
+		return resources, nil
+	}
+	n, err := strconv.Atoi(subscription)
+	if err != nil || n < 1 || n > resources {
+		return 0, fmt.Errorf("subscription should be wildcard, all, or a number of resources between 1 and %v, got %q", resources, subscription)
+	}
+	return n, nil
+}
+
+// The resources each client subscribes to, chosen with the seed so a run can be
+// repeated. A nil list is a wildcard subscription.
+func subscriptions(c Config) [][]string {
+	size, _ := subscriptionSize(c.Subscription, c.Resources)
+	random := rand.New(rand.NewSource(c.Seed))
+	all := []string{}
+	for i := 0; i < c.Resources; i++ {
+		all = append(all, c.resource(i))
+	}
+	subs := make([][]string, c.Clients)
+	for i := range subs {
+		switch {
+		case size < 0:
+			subs[i] = nil
+		case size == c.Resources:
+			subs[i] = all
+		default:
+			names := []string{}
+			for _, j := range random.Perm(c.Resources)[:size] {
+				names = append(names, all[j])
+			}
+			subs[i] = names
+		}
+	}
+	return subs
+}
+
+// Keeps count of the errors seen through the run. The updates and their
+// deliveries are matched by the adapter's Pushes.
+type recorder struct {
+	sync.Mutex
+	streamErrors int
+	errors       types.DistinctErrors
+}
+
+func newRecorder() *recorder {
+	return &recorder{errors: types.DistinctErrors{Limit: maxErrors}}
+}
+
+func (r *recorder) streamError(err error) {
+	r.Lock()
+	defer r.Unlock()
+	r.streamErrors++
+	r.errors.Add(err)
+}
+
+func (r *recorder) error(err error) {
+	r.Lock()
+	defer r.Unlock()
+	r.errors.Add(err)
+}
+
+func Run(c Config) (results types.LoadResults, err error) {
+	if err = c.validate(); err != nil {
+		return results, err
+	}
+	suite := runner.NewSuite(c.Variant, false)
+	if suite == nil {
+		return results, fmt.Errorf("unknown variant: %v", c.Variant)
+	}
+	if _, err = parser.ServiceToTypeURL(c.Service); err != nil {
+		return results, err
+	}
+	results = types.LoadResults{
+		Variant:      string(c.Variant),
+		Service:      c.Service,
+		Clients:      c.Clients,
+		Nodes:        c.Nodes,
+		Connections:  c.Connections,
+		Resources:    c.Resources,
+		Subscription: c.Subscription,
+		Duration:     c.Duration,
+	}
+
+	adapter := runner.FreshRunner()
+	// the clients share the adapter's, to time its updates until they arrive.
+	adapter.Pushes = runner.NewPushes()
+	if err = adapter.ConnectClient("adapter", c.Adapter); err != nil {
+		return results, fmt.Errorf("cannot connect to adapter: %v", err)
+	}
+	defer adapter.ClearState()
+	connections := []*runner.Runner{}
+	for i := 0; i < c.Connections; i++ {
+		base := runner.FreshRunner(adapter)
+		base.Target = &runner.ClientConfig{}
+		if err = base.ConnectClient("target", c.Target); err != nil {
+			return results, fmt.Errorf("cannot connect to target: %v", err)
+		}
+		base.Aggregated = suite.Aggregated
+		base.Incremental = suite.Incremental
+		// streams stay open for the whole run.
+		base.StreamTimeout = c.Duration + c.Grace + time.Minute
+		connections = append(connections, base)
+	}
+
+	names := []string{}
+	for i := 0; i < c.Resources; i++ {
+		names = append(names, c.resource(i))
+	}
+	for i := 0; i < c.Nodes; i++ {
+		if err = adapter.TargetSetupForNodeWithServiceResourcesAndVersion(c.node(i), c.Service, strings.Join(names, ","), "1"); err != nil {
+			return results, err
+		}
+	}
+	rec := newRecorder()
+	subs := subscriptions(c)
+	// how many clients on each node receive each resource.
+	subscribers := map[string]map[string]int{}
+	clients := []*runner.Runner{}
+	for i := 0; i < c.Clients; i++ {
+		client := runner.FreshRunner(connections[i%c.Connections])
+		client.NodeID = c.node(i % c.Nodes)
+		client.StreamTimeout = connections[0].StreamTimeout
+		node := client.NodeID
+		if subscribers[node] == nil {
+			subscribers[node] = map[string]int{}
+		}
+		subscribed := subs[i]
+		if subscribed == nil {
+			subscribed = names
+		}
+		for _, name := range subscribed {
+			subscribers[node][name]++
+		}
+		if err := client.ClientSubscribesToServiceForResources(c.Service, subs[i]); err != nil {
+			rec.streamError(err)
+			continue
+		}
+		results.StreamsOpened++
+		clients = append(clients, client)
+		go func(errs chan error) {
+			for err := range errs {
+				rec.streamError(err)
+			}
+		}(client.Service.Channels.Err)
+	}
+	log.Info().Msgf("Opened %v of %v streams", results.StreamsOpened, c.Clients)
+
+	var memory *memorySampler
+	if c.ServerPID > 0 {
+		memory, err = sampleMemory(c.ServerPID, time.Second)
+		if err != nil {
+			return results, err
+		}
+	}
+
+	ticker := time.NewTicker(c.UpdateInterval)
+	end := time.After(c.Duration)
+	tick := 0
+churn:
+	for {
+		select {
+		case <-end:
+			break churn
+		case <-ticker.C:
+			tick++
+			resource := names[(tick-1)%len(names)]
+			version := strconv.Itoa(tick + 1)
+			for i := 0; i < c.Nodes; i++ {
+				node := c.node(i)
+				if err := adapter.ResourceOfServiceIsUpdatedToVersionForNode(resource, c.Service, version, node); err != nil {
+					results.AdapterErrors++
+					rec.error(err)
+					continue
+				}
+				results.Updates++
+				results.ExpectedDeliveries += subscribers[node][resource]
+			}
+		}
+	}
+	ticker.Stop()
+	time.Sleep(c.Grace)
+
+	for _, client := range clients {
+		client.CloseStream()
+	}
+	if memory != nil {
+		results.ServerMemory = memory.stop()
+	}
+
+	samples := adapter.Pushes.Samples()
+	rec.Lock()
+	defer rec.Unlock()
+	results.StreamErrors = rec.streamErrors
+	results.Deliveries = len(samples)
+	results.Latency = types.NewLatency(samples)
+	results.Errors = rec.errors.Messages
+	return results, nil
+}
@@ -0,0 +1,68 @@
+package load
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSubscriptionSize(t *testing.T) {
+	cases := map[string]int{"wildcard": -1, "all": 10, "3": 3}
+	for subscription, expected := range cases {
+		size, err := subscriptionSize(subscription, 10)
+		if err != nil {
+			t.Errorf("Error for subscription %q when expecting no err: %v", subscription, err)
+		}
+		if size != expected {
+			t.Errorf("Subscription %q has wrong size. expected: %v, actual: %v", subscription, expected, size)
+		}
+	}
+	for _, subscription := range []string{"some", "0", "11"} {
+		if _, err := subscriptionSize(subscription, 10); err == nil {
+			t.Errorf("No error for subscription %q when expecting one", subscription)
+		}
+	}
+}
+
+func TestSubscriptions(t *testing.T) {
+	c := Config{Clients: 5, Resources: 10, Subscription: "3", Seed: 7}
+	first := subscriptions(c)
+	again := subscriptions(c)
+	for i := range first {
+		if len(first[i]) != 3 {
+			t.Fatalf("Client %v not subscribed to 3 resources: %v", i, first[i])
+		}
+		if strings.Join(first[i], ",") != strings.Join(again[i], ",") {
+			t.Errorf("Subscriptions differ with the same seed: %v and %v", first[i], again[i])
+		}
+	}
+	c.Subscription = "wildcard"
+	if subs := subscriptions(c); subs[0] != nil {
+		t.Errorf("Wildcard subscription should be nil, got: %v", subs[0])
+	}
+}
+
+func TestRecorderKeepsDistinctErrors(t *testing.T) {
+	rec := newRecorder()
+	for i := 0; i < 3; i++ {
+		rec.streamError(errors.New("stream closed"))
+	}
+	rec.error(errors.New("adapter unavailable"))
+	if rec.streamErrors != 3 || len(rec.errors.Messages) != 2 {
+		t.Errorf("Errors not kept as expected: %v stream errors, %v", rec.streamErrors, rec.errors.Messages)
+	}
+}
+
+func TestParseResidentMemory(t *testing.T) {
+	status := "Name:\tserver\nVmPeak:\t  4096 kB\nVmRSS:\t  1024 kB\n"
+	bytes, err := parseResidentMemory(strings.NewReader(status))
+	if err != nil {
+		t.Fatalf("Error parsing memory when expecting no err: %v", err)
+	}
+	if bytes != 1024*1024 {
+		t.Errorf("Wrong resident memory. expected: %v, actual: %v", 1024*1024, bytes)
+	}
+	if _, err := parseResidentMemory(strings.NewReader("Name:\tserver\n")); err == nil {
+		t.Error("No error for status without VmRSS when expecting one")
+	}
+}
@@ -0,0 +1,100 @@
+package load
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ii/xds-test-harness/internal/types"
+)
+
+// Samples the resident memory of a process, as the kernel reports it in
+// /proc/<pid>/status, so only a server running on the same Linux machine can be
+// watched.
+type memorySampler struct {
+	sync.Mutex
+	pid   int
+	usage types.MemoryUsage
+	done  chan bool
+	wg    sync.WaitGroup
+}
+
+func sampleMemory(pid int, every time.Duration) (*memorySampler, error) {
+	start, err := residentMemory(pid)
+	if err != nil {
+		return nil, err
+	}
+	m := &memorySampler{
+		pid:   pid,
+		usage: types.MemoryUsage{Start: start, Peak: start, End: start},
+		done:  make(chan bool),
+	}
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.done:
+				return
+			case <-ticker.C:
+				m.sample()
+			}
+		}
+	}()
+	return m, nil
+}
+
+func (m *memorySampler) sample() {
+	rss, err := residentMemory(m.pid)
+	if err != nil {
+		return
+	}
+	m.Lock()
+	defer m.Unlock()
+	m.usage.End = rss
+	if rss > m.usage.Peak {
+		m.usage.Peak = rss
+	}
+}
+
+func (m *memorySampler) stop() *types.MemoryUsage {
+	close(m.done)
+	m.wg.Wait()
+	m.sample()
+	m.Lock()
+	defer m.Unlock()
+	usage := m.usage
+	return &usage
+}
+
+func residentMemory(pid int) (uint64, error) {
+	f, err := os.Open(fmt.Sprintf("/proc/%v/status", pid))
+	if err != nil {
+		return 0, fmt.Errorf("cannot read memory of server: %v", err)
+	}
+	defer f.Close()
+	return parseResidentMemory(f)
+}
+
+// Reads VmRSS, given in kB, from the contents of /proc/<pid>/status.
+func parseResidentMemory(f io.Reader) (uint64, error) {
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "VmRSS:" {
+			kb, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("cannot parse memory of server from %q: %v", scanner.Text(), err)
+			}
+			return kb * 1024, nil
+		}
+	}
+	return 0, fmt.Errorf("no VmRSS in status of server")
+}
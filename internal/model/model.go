@@ -0,0 +1,286 @@
+// Package model checks a target against a reference model of xDS, with random
+// sequences of adapter and client operations. The model knows what a client
+// should end up with after each operation, so any sequence can be checked, not
+// only the paths the features thought of. A failing sequence is shrunk to the
+// fewest operations that still fail, and written out as a scenario.
+package model
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Kind string
+
+const (
+	// Sets the whole state of the service. Only ever the first operation, so the
+	// model needn't guess which resources a target counts as changed by it.
+	SetState    Kind = "set"
+	Add         Kind = "add"
+	Update      Kind = "update"
+	Remove      Kind = "remove"
+	Subscribe   Kind = "subscribe"
+	Unsubscribe Kind = "unsubscribe"
+	Reconnect   Kind = "reconnect"
+)
+
+// A single operation. Resources changed through the adapter get the version,
+// which goes up with each operation that has one.
+type Op struct {
+	Kind      Kind     `json:"kind"`
+	Resources []string `json:"resources,omitempty"`
+	Version   string   `json:"version,omitempty"`
+	// A wildcard subscription.
+	Wildcard bool `json:"wildcard,omitempty"`
+}
+
+func (o Op) String() string {
+	switch {
+	case o.Wildcard:
+		return fmt.Sprintf("%v *", o.Kind)
+	case o.Version != "":
+		return fmt.Sprintf("%v %v@%v", o.Kind, strings.Join(o.Resources, ","), o.Version)
+	case len(o.Resources) > 0:
+		return fmt.Sprintf("%v %v", o.Kind, strings.Join(o.Resources, ","))
+	}
+	return string(o.Kind)
+}
+
+// The state of the target and the client's subscription, as the operations so
+// far leave them.
+type Model struct {
+	// The version each resource was last changed at.
+	Resources  map[string]string
+	Subscribed bool
+	Wildcard   bool
+	Names      map[string]bool
+	// Resources removed from the target while the client was subscribed to them.
+	Removed map[string]bool
+}
+
+func New() *Model {
+	return &Model{
+		Resources: map[string]string{},
+		Names:     map[string]bool{},
+		Removed:   map[string]bool{},
+	}
+}
+
+// Applies the operation, or says why it can't follow the ones before it and
+// leaves the model as it was.
+func (m *Model) Apply(op Op) error {
+	next := m.clone()
+	if err := next.apply(op); err != nil {
+		return err
+	}
+	*m = *next
+	return nil
+}
+
+func (m *Model) clone() *Model {
+	c := New()
+	for resource, version := range m.Resources {
+		c.Resources[resource] = version
+	}
+	for resource := range m.Names {
+		c.Names[resource] = true
+	}
+	for resource := range m.Removed {
+		c.Removed[resource] = true
+	}
+	c.Subscribed = m.Subscribed
+	c.Wildcard = m.Wildcard
+	return c
+}
+
+func (m *Model) apply(op Op) error {
+	switch op.Kind {
+	case SetState:
+		if len(m.Resources) > 0 || m.Subscribed {
+			return fmt.Errorf("state can only be set first")
+		}
+		for _, resource := range op.Resources {
+			m.Resources[resource] = op.Version
+		}
+	case Add:
+		for _, resource := range op.Resources {
+			if _, ok := m.Resources[resource]; ok {
+				return fmt.Errorf("%v already exists", resource)
+			}
+			m.Resources[resource] = op.Version
+			delete(m.Removed, resource)
+		}
+	case Update:
+		for _, resource := range op.Resources {
+			if _, ok := m.Resources[resource]; !ok {
+				return fmt.Errorf("%v does not exist", resource)
+			}
+			m.Resources[resource] = op.Version
+		}
+	case Remove:
+		for _, resource := range op.Resources {
+			if _, ok := m.Resources[resource]; !ok {
+				return fmt.Errorf("%v does not exist", resource)
+			}
+			delete(m.Resources, resource)
+			if m.subscribedTo(resource) {
+				m.Removed[resource] = true
+			}
+		}
+	case Subscribe:
+		if m.Wildcard {
+			return fmt.Errorf("already subscribed to everything")
+		}
+		if op.Wildcard {
+			if m.Subscribed {
+				return fmt.Errorf("only the first subscription can be a wildcard")
+			}
+			m.Wildcard = true
+		}
+		for _, resource := range op.Resources {
+			m.Names[resource] = true
+		}
+		m.Subscribed = true
+	case Unsubscribe:
+		if !m.Subscribed || m.Wildcard {
+			return fmt.Errorf("no named subscription to unsubscribe from")
+		}
+		for _, resource := range op.Resources {
+			if !m.Names[resource] {
+				return fmt.Errorf("not subscribed to %v", resource)
+			}
+			delete(m.Names, resource)
+			delete(m.Removed, resource)
+		}
+		// with no names left, a sotw request would be a wildcard one.
+		if len(m.Names) == 0 {
+			return fmt.Errorf("cannot unsubscribe from every resource")
+		}
+	case Reconnect:
+		if !m.Subscribed {
+			return fmt.Errorf("no stream to reconnect")
+		}
+		// a new stream starts with nothing to remove.
+		m.Removed = map[string]bool{}
+	default:
+		return fmt.Errorf("unknown operation: %v", op.Kind)
+	}
+	return nil
+}
+
+func (m *Model) subscribedTo(resource string) bool {
+	return m.Subscribed && (m.Wildcard || m.Names[resource])
+}
+
+// The resources the client should have, with the least version of each.
+func (m *Model) Expected() map[string]string {
+	expected := map[string]string{}
+	for resource, version := range m.Resources {
+		if m.subscribedTo(resource) {
+			expected[resource] = version
+		}
+	}
+	return expected
+}
+
+// The resources the client should no longer have, as they were removed.
+func (m *Model) Gone() []string {
+	return sortedKeys(m.Removed)
+}
+
+// The names of the current subscription, sorted.
+func (m *Model) Subscription() []string {
+	return sortedKeys(m.Names)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := []string{}
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Whether every operation can follow the ones before it.
+func Valid(ops []Op) bool {
+	m := New()
+	for _, op := range ops {
+		if m.Apply(op) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// The resources operations draw from.
+var pool = []string{"A", "B", "C", "D", "E", "F", "G", "H"}
+
+// Generates a random sequence of length operations, which always starts by setting
+// the state and, soon after, subscribing.
+func Generate(random *rand.Rand, length int) []Op {
+	m := New()
+	version := 1
+	next := func() string {
+		version++
+		return strconv.Itoa(version)
+	}
+	initial := pick(random, pool, 1+random.Intn(3))
+	ops := []Op{{Kind: SetState, Resources: initial, Version: "1"}}
+	_ = m.Apply(ops[0])
+	for len(ops) < length {
+		var op Op
+		existing, missing := []string{}, []string{}
+		for _, resource := range pool {
+			if _, ok := m.Resources[resource]; ok {
+				existing = append(existing, resource)
+			} else {
+				missing = append(missing, resource)
+			}
+		}
+		switch choice := random.Intn(10); {
+		case !m.Subscribed && random.Intn(3) == 0:
+			op = Op{Kind: Subscribe, Wildcard: true}
+		case !m.Subscribed || (choice == 0 && !m.Wildcard):
+			op = Op{Kind: Subscribe, Resources: pick(random, pool, 1+random.Intn(3))}
+		case choice == 1 && !m.Wildcard && len(m.Names) > 1:
+			op = Op{Kind: Unsubscribe, Resources: pick(random, m.Subscription(), 1)}
+		case choice == 2:
+			op = Op{Kind: Reconnect}
+		case choice <= 4 && len(missing) > 0:
+			op = Op{Kind: Add, Resources: pick(random, missing, 1), Version: next()}
+		case choice <= 6 && len(existing) > 1:
+			op = Op{Kind: Remove, Resources: pick(random, existing, 1)}
+		case len(existing) > 0:
+			op = Op{Kind: Update, Resources: pick(random, existing, 1), Version: next()}
+		default:
+			continue
+		}
+		if m.Apply(op) != nil {
+			continue
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// Picks n of the names at random, in the order they are given.
+func pick(random *rand.Rand, names []string, n int) []string {
+	if n > len(names) {
+		n = len(names)
+	}
+	chosen := map[string]bool{}
+	for _, i := range random.Perm(len(names))[:n] {
+		chosen[names[i]] = true
+	}
+	picked := []string{}
+	for _, name := range names {
+		if chosen[name] {
+			picked = append(picked, name)
+		}
+	}
+	return picked
+}
@@ -0,0 +1,201 @@
+package model
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ii/xds-test-harness/internal/types"
+)
+
+func TestApply(t *testing.T) {
+	m := New()
+	ops := []Op{
+		{Kind: SetState, Resources: []string{"A", "B"}, Version: "1"},
+		{Kind: Subscribe, Resources: []string{"A", "C"}},
+		{Kind: Add, Resources: []string{"C"}, Version: "2"},
+		{Kind: Update, Resources: []string{"A"}, Version: "3"},
+		{Kind: Remove, Resources: []string{"C"}},
+	}
+	for _, op := range ops {
+		if err := m.Apply(op); err != nil {
+			t.Fatalf("Couldn't apply %v: %v", op, err)
+		}
+	}
+	if expected := m.Expected(); !reflect.DeepEqual(expected, map[string]string{"A": "3"}) {
+		t.Errorf("Wrong resources expected: %v", expected)
+	}
+	if gone := m.Gone(); !reflect.DeepEqual(gone, []string{"C"}) {
+		t.Errorf("Wrong resources gone: %v", gone)
+	}
+
+	// a reconnected client starts over, with nothing to be told is removed.
+	if err := m.Apply(Op{Kind: Reconnect}); err != nil {
+		t.Fatalf("Couldn't reconnect: %v", err)
+	}
+	if gone := m.Gone(); len(gone) != 0 {
+		t.Errorf("Resources still gone after reconnecting: %v", gone)
+	}
+}
+
+func TestApplyRejectsOperationsThatCannotFollow(t *testing.T) {
+	set := Op{Kind: SetState, Resources: []string{"A"}, Version: "1"}
+	invalid := map[string][]Op{
+		"state set twice":           {set, set},
+		"adding what exists":        {set, {Kind: Add, Resources: []string{"A"}, Version: "2"}},
+		"updating what's missing":   {set, {Kind: Update, Resources: []string{"B"}, Version: "2"}},
+		"removing what's missing":   {set, {Kind: Remove, Resources: []string{"B"}}},
+		"late wildcard":             {set, {Kind: Subscribe, Resources: []string{"A"}}, {Kind: Subscribe, Wildcard: true}},
+		"subscribing after *":       {set, {Kind: Subscribe, Wildcard: true}, {Kind: Subscribe, Resources: []string{"A"}}},
+		"unsubscribing from *":      {set, {Kind: Subscribe, Wildcard: true}, {Kind: Unsubscribe, Resources: []string{"A"}}},
+		"unsubscribing from all":    {set, {Kind: Subscribe, Resources: []string{"A"}}, {Kind: Unsubscribe, Resources: []string{"A"}}},
+		"reconnecting with no sub":  {set, {Kind: Reconnect}},
+		"unsubscribing from others": {set, {Kind: Subscribe, Resources: []string{"A", "B"}}, {Kind: Unsubscribe, Resources: []string{"C"}}},
+	}
+	for name, ops := range invalid {
+		if Valid(ops) {
+			t.Errorf("Sequence with %v was valid: %v", name, ops)
+		}
+	}
+
+	// a failed operation leaves the model as it was.
+	m := New()
+	_ = m.Apply(set)
+	if err := m.Apply(Op{Kind: Add, Resources: []string{"B", "A"}, Version: "2"}); err == nil {
+		t.Fatalf("Added a resource that exists")
+	}
+	if _, ok := m.Resources["B"]; ok {
+		t.Errorf("Resource added by a failed operation")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	for seed := int64(0); seed < 50; seed++ {
+		ops := Generate(rand.New(rand.NewSource(seed)), 20)
+		if len(ops) != 20 || ops[0].Kind != SetState {
+			t.Fatalf("Sequence %v not generated as expected: %v", seed, ops)
+		}
+		if !Valid(ops) {
+			t.Errorf("Sequence %v is not valid: %v", seed, ops)
+		}
+		again := Generate(rand.New(rand.NewSource(seed)), 20)
+		if !reflect.DeepEqual(ops, again) {
+			t.Errorf("Sequence %v differs when generated again", seed)
+		}
+	}
+}
+
+func TestOperationSteps(t *testing.T) {
+	m := New()
+	_ = m.Apply(Op{Kind: SetState, Resources: []string{"A", "B"}, Version: "1"})
+	_ = m.Apply(Op{Kind: Subscribe, Resources: []string{"A"}})
+	subscribe := Op{Kind: Subscribe, Resources: []string{"B"}}
+	_ = m.Apply(subscribe)
+
+	// a sotw client sends its whole subscription, a delta client what it adds.
+	sotw := operationSteps(subscribe, m, "CDS", false)
+	if len(sotw) != 1 || sotw[0].text != `the Client subscribes to resources "A,B" for "CDS"` {
+		t.Errorf("Wrong sotw subscribe step: %v", sotw)
+	}
+	delta := operationSteps(subscribe, m, "CDS", true)
+	if len(delta) != 1 || delta[0].text != `the Client subscribes to resources "B" for "CDS"` {
+		t.Errorf("Wrong delta subscribe step: %v", delta)
+	}
+
+	unsubscribe := Op{Kind: Unsubscribe, Resources: []string{"A"}}
+	_ = m.Apply(unsubscribe)
+	sotw = operationSteps(unsubscribe, m, "CDS", false)
+	if len(sotw) != 1 || sotw[0].text != `the Client subscribes to resources "B" for "CDS"` {
+		t.Errorf("Wrong sotw unsubscribe step: %v", sotw)
+	}
+	delta = operationSteps(unsubscribe, m, "CDS", true)
+	if len(delta) != 1 || delta[0].text != `the Client unsubscribes from resource "A" for service "CDS"` {
+		t.Errorf("Wrong delta unsubscribe step: %v", delta)
+	}
+}
+
+func TestCheckSteps(t *testing.T) {
+	m := New()
+	for _, op := range []Op{
+		{Kind: SetState, Resources: []string{"A", "B"}, Version: "1"},
+		{Kind: Subscribe, Wildcard: true},
+		{Kind: Remove, Resources: []string{"B"}},
+	} {
+		_ = m.Apply(op)
+	}
+	texts := func(steps []step) []string {
+		t := []string{}
+		for _, s := range steps {
+			t = append(t, s.text)
+		}
+		return t
+	}
+	has := `the Client has the resource "A" of "RDS" at version "1" or later`
+	// only a delta client, or a sotw client of LDS or CDS, can tell B is gone.
+	if steps := texts(checkSteps(m, "RDS", false)); !reflect.DeepEqual(steps, []string{has}) {
+		t.Errorf("Wrong sotw RDS checks: %v", steps)
+	}
+	expected := []string{has, `the Client does not have the resource "B" of "RDS"`}
+	if steps := texts(checkSteps(m, "RDS", true)); !reflect.DeepEqual(steps, expected) {
+		t.Errorf("Wrong delta RDS checks: %v", steps)
+	}
+}
+
+func TestShrink(t *testing.T) {
+	ops := []Op{
+		{Kind: SetState, Resources: []string{"A", "B", "C"}, Version: "1"},
+		{Kind: Subscribe, Resources: []string{"A", "B"}},
+		{Kind: Update, Resources: []string{"B"}, Version: "2"},
+		{Kind: Reconnect},
+		{Kind: Add, Resources: []string{"D"}, Version: "3"},
+		{Kind: Remove, Resources: []string{"A"}},
+		{Kind: Update, Resources: []string{"C"}, Version: "4"},
+	}
+	// a target that fails whenever A is removed while subscribed to.
+	fails := func(ops []Op) bool {
+		m := New()
+		for _, op := range ops {
+			_ = m.Apply(op)
+		}
+		return len(m.Gone()) > 0
+	}
+	shrunk := shrink(ops, fails, 100)
+	expected := []Op{
+		{Kind: SetState, Resources: []string{"A"}, Version: "1"},
+		{Kind: Subscribe, Resources: []string{"A"}},
+		{Kind: Remove, Resources: []string{"A"}},
+	}
+	if !reflect.DeepEqual(shrunk, expected) {
+		t.Errorf("Sequence not shrunk as expected: %v", opStrings(shrunk))
+	}
+
+	// shrinking stops when it runs out of tries.
+	if shrunk := shrink(ops, fails, 0); !reflect.DeepEqual(shrunk, ops) {
+		t.Errorf("Sequence shrunk with no tries: %v", opStrings(shrunk))
+	}
+}
+
+func TestScenario(t *testing.T) {
+	ops := []Op{
+		{Kind: SetState, Resources: []string{"A"}, Version: "1"},
+		{Kind: Subscribe, Resources: []string{"A"}},
+		{Kind: Update, Resources: []string{"A"}, Version: "2"},
+		{Kind: Reconnect},
+	}
+	scenario := Scenario(ops, 2, types.IncrementalAggregated, "CDS", 7)
+	expected := `  @model
+  @incremental @aggregated
+  Scenario: [CDS] Sequence from seed 7
+    Given a target setup with service "CDS", resources "A", and starting version "1"
+    When the Client subscribes to resources "A" for "CDS"
+    And the resource "A" of service "CDS" is updated to version "2"
+    Then the Client has the resource "A" of "CDS" at version "2" or later
+`
+	if scenario != expected {
+		t.Errorf("Wrong scenario. expected:\n%v\nactual:\n%v", expected, scenario)
+	}
+	if feature := Feature([]string{scenario}); !strings.HasPrefix(feature, "Feature: ") || !strings.Contains(feature, scenario) {
+		t.Errorf("Scenario missing from feature:\n%v", feature)
+	}
+}
@@ -0,0 +1,225 @@
+package model
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/ii/xds-test-harness/internal/runner"
+	"github.com/ii/xds-test-harness/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+type Config struct {
+	Target   string
+	Adapter  string
+	Variants []types.Variant
+	Service  string
+	// Sequences to generate for each variant, and how many operations each has.
+	Runs   int
+	Length int
+	Seed   int64
+	// Each sequence runs on a node of its own, numbered after this prefix.
+	NodePrefix string
+	// Most sequences to run while shrinking a failing one.
+	MaxShrinks int
+}
+
+// Runs sequences against the target, each on a node of its own, and checks the
+// client against the model after every operation.
+type checker struct {
+	base        *runner.Runner
+	service     string
+	incremental bool
+	nodePrefix  string
+	sequences   int
+}
+
+// The operation a sequence failed at, and how.
+type failure struct {
+	at   int
+	step string
+	err  error
+}
+
+func (c *checker) check(ops []Op) *failure {
+	c.sequences++
+	client := runner.FreshRunner(c.base)
+	client.NodeID = fmt.Sprintf("%v-%v", c.nodePrefix, c.sequences)
+	defer func() {
+		client.CloseStream()
+		client.ClearState()
+	}()
+
+	m := New()
+	for i, op := range ops {
+		if err := m.Apply(op); err != nil {
+			return &failure{i, op.String(), fmt.Errorf("invalid sequence: %v", err)}
+		}
+		for _, s := range operationSteps(op, m, c.service, c.incremental) {
+			if err := s.run(client); err != nil {
+				return &failure{i, s.text, err}
+			}
+		}
+		for _, s := range checkSteps(m, c.service, c.incremental) {
+			if err := s.run(client); err != nil {
+				return &failure{i, s.text, err}
+			}
+		}
+	}
+	return nil
+}
+
+// Shrinks a failing sequence to one that fails with fewer operations, or fewer
+// resources in them, by dropping what it can while the sequence still fails.
+// The first operation, setting the state, is always kept.
+func shrink(ops []Op, fails func([]Op) bool, budget int) []Op {
+	tries := 0
+	try := func(candidate []Op) bool {
+		if tries >= budget || !Valid(candidate) {
+			return false
+		}
+		tries++
+		return fails(candidate)
+	}
+	for chunk := len(ops) / 2; chunk >= 1; {
+		shrunk := false
+		for start := 1; start+chunk <= len(ops); start++ {
+			candidate := append(append([]Op{}, ops[:start]...), ops[start+chunk:]...)
+			if try(candidate) {
+				ops, shrunk = candidate, true
+				break
+			}
+		}
+		if !shrunk {
+			chunk /= 2
+		}
+	}
+	for i := 0; i < len(ops); i++ {
+		for j := 0; len(ops[i].Resources) > 1 && j < len(ops[i].Resources); j++ {
+			candidate := append([]Op{}, ops...)
+			resources := append(append([]string{}, ops[i].Resources[:j]...), ops[i].Resources[j+1:]...)
+			candidate[i] = Op{Kind: ops[i].Kind, Resources: resources, Version: ops[i].Version, Wildcard: ops[i].Wildcard}
+			if try(candidate) {
+				ops = candidate
+				j--
+			}
+		}
+	}
+	return ops
+}
+
+func Run(c Config) (results []types.ModelResults, reproducers []string, err error) {
+	base := runner.FreshRunner()
+	if err = base.ConnectClient("adapter", c.Adapter); err != nil {
+		return nil, nil, fmt.Errorf("cannot connect to adapter: %v", err)
+	}
+	if err = base.ConnectClient("target", c.Target); err != nil {
+		return nil, nil, fmt.Errorf("cannot connect to target: %v", err)
+	}
+	for _, variant := range c.Variants {
+		suite := runner.NewSuite(variant, false)
+		if suite == nil {
+			return nil, nil, fmt.Errorf("unknown variant: %v", variant)
+		}
+		base.Aggregated = suite.Aggregated
+		base.Incremental = suite.Incremental
+		checker := &checker{
+			base:        base,
+			service:     c.Service,
+			incremental: suite.Incremental,
+			nodePrefix:  fmt.Sprintf("%v-%v", c.NodePrefix, runner.VariantSuffix(variant)),
+		}
+		result := types.ModelResults{Variant: string(variant), Service: c.Service}
+		for i := 0; i < c.Runs; i++ {
+			seed := c.Seed + int64(i)
+			ops := Generate(rand.New(rand.NewSource(seed)), c.Length)
+			result.Runs++
+			failed := checker.check(ops)
+			if failed == nil {
+				result.Passed++
+				continue
+			}
+			log.Info().Msgf("[%v] sequence %v failed at %v: %v", variant, seed, failed.step, failed.err)
+			found := types.ModelFailure{
+				Seed:  seed,
+				Ops:   opStrings(ops),
+				Step:  failed.step,
+				Error: failed.err.Error(),
+			}
+			// the operations after the failing one can't matter.
+			ops = ops[:failed.at+1]
+			if checker.check(ops) == nil {
+				found.Flaky = true
+				result.Failures = append(result.Failures, found)
+				continue
+			}
+			ops = shrink(ops, func(candidate []Op) bool { return checker.check(candidate) != nil }, c.MaxShrinks)
+			last := checker.check(ops)
+			if last == nil {
+				// the shrunk sequence only failed some of the time.
+				found.Flaky = true
+				result.Failures = append(result.Failures, found)
+				continue
+			}
+			found.Shrunk = opStrings(ops)
+			found.Step = last.step
+			found.Error = last.err.Error()
+			result.Failures = append(result.Failures, found)
+			reproducers = append(reproducers, Scenario(ops, last.at, variant, c.Service, seed))
+		}
+		results = append(results, result)
+	}
+	return results, reproducers, nil
+}
+
+func opStrings(ops []Op) []string {
+	strs := []string{}
+	for _, op := range ops {
+		strs = append(strs, op.String())
+	}
+	return strs
+}
+
+// Writes the sequence, up to the operation it failed at, as a scenario of the
+// variant, ending with the checks that failed.
+func Scenario(ops []Op, failedAt int, variant types.Variant, service string, seed int64) string {
+	incremental := strings.HasPrefix(string(variant), "incremental")
+	var b strings.Builder
+	tags := []string{}
+	for _, word := range strings.Split(string(variant), " ") {
+		tags = append(tags, "@"+word)
+	}
+	fmt.Fprintf(&b, "  @model\n  %v\n", strings.Join(tags, " "))
+	fmt.Fprintf(&b, "  Scenario: [%v] Sequence from seed %v\n", service, seed)
+	m := New()
+	keyword := "Given"
+	for i, op := range ops[:failedAt+1] {
+		_ = m.Apply(op)
+		for _, s := range operationSteps(op, m, service, incremental) {
+			fmt.Fprintf(&b, "    %v %v\n", keyword, s.text)
+			keyword = "And"
+		}
+		if i == 0 {
+			keyword = "When"
+		}
+	}
+	keyword = "Then"
+	for _, s := range checkSteps(m, service, incremental) {
+		fmt.Fprintf(&b, "    %v %v\n", keyword, s.text)
+		keyword = "And"
+	}
+	return b.String()
+}
+
+// A feature of the scenarios the model wrote, to run with --features.
+func Feature(scenarios []string) string {
+	var b strings.Builder
+	b.WriteString("Feature: Model Reproducers\n")
+	b.WriteString("  Sequences of operations the model command found the target failing at,\n")
+	b.WriteString("  shrunk to as few as still fail.\n")
+	for _, scenario := range scenarios {
+		b.WriteString("\n" + scenario)
+	}
+	return b.String()
+}
@@ -0,0 +1,119 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ii/xds-test-harness/internal/runner"
+)
+
+// A step of a scenario: its text, as a feature would have it, and how the runner
+// takes it. Operations and checks are both made of steps, so what the model runs
+// is what it writes out when a sequence fails.
+type step struct {
+	text string
+	run  func(r *runner.Runner) error
+}
+
+// The steps that take the client, or the target through the adapter, through the
+// operation. The model has to have had the operation applied already, as a sotw
+// client sends its whole subscription with each change to it.
+func operationSteps(op Op, m *Model, service string, incremental bool) []step {
+	resources := strings.Join(op.Resources, ",")
+	switch op.Kind {
+	case SetState:
+		return []step{{
+			fmt.Sprintf("a target setup with service %q, resources %q, and starting version %q", service, resources, op.Version),
+			func(r *runner.Runner) error {
+				return r.TargetSetupWithServiceResourcesAndVersion(service, resources, op.Version)
+			},
+		}}
+	case Add:
+		return []step{{
+			fmt.Sprintf("the resource %q is added to the %q with version %q", resources, service, op.Version),
+			func(r *runner.Runner) error {
+				return r.ResourceIsAddedToServiceWithVersion(resources, service, op.Version)
+			},
+		}}
+	case Update:
+		return []step{{
+			fmt.Sprintf("the resource %q of service %q is updated to version %q", resources, service, op.Version),
+			func(r *runner.Runner) error {
+				return r.ResourceOfServiceIsUpdatedToVersion(resources, service, op.Version)
+			},
+		}}
+	case Remove:
+		return []step{{
+			fmt.Sprintf("the resource %q is removed from the %q", resources, service),
+			func(r *runner.Runner) error { return r.ResourceIsRemovedFromTheService(resources, service) },
+		}}
+	case Subscribe:
+		if op.Wildcard {
+			return []step{{
+				fmt.Sprintf("the Client does a wildcard subscription to %q", service),
+				func(r *runner.Runner) error { return r.ClientDoesAWildcardSubscriptionToService(service) },
+			}}
+		}
+		// a delta client subscribes to more, a sotw client to everything at once.
+		names := op.Resources
+		if !incremental {
+			names = m.Subscription()
+		}
+		return []step{subscribeStep(names, service)}
+	case Unsubscribe:
+		if !incremental {
+			return []step{subscribeStep(m.Subscription(), service)}
+		}
+		return []step{{
+			fmt.Sprintf("the Client unsubscribes from resource %q for service %q", resources, service),
+			func(r *runner.Runner) error { return r.ClientUnsubscribesFromResourceForService(resources, service) },
+		}}
+	case Reconnect:
+		return []step{{"the Client reconnects", (*runner.Runner).TheClientReconnects}}
+	}
+	return nil
+}
+
+func subscribeStep(names []string, service string) step {
+	resources := strings.Join(names, ",")
+	return step{
+		fmt.Sprintf("the Client subscribes to resources %q for %q", resources, service),
+		func(r *runner.Runner) error {
+			return r.ClientSubscribesToServiceForResources(service, strings.Split(resources, ","))
+		},
+	}
+}
+
+// The steps checking the client has what the model says it should. A sotw
+// client can only tell a resource is gone with LDS and CDS, whose every
+// response has the whole state.
+func checkSteps(m *Model, service string, incremental bool) []step {
+	steps := []step{}
+	expected := m.Expected()
+	resources := []string{}
+	for resource := range expected {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+	for _, resource := range resources {
+		resource, version := resource, expected[resource]
+		steps = append(steps, step{
+			fmt.Sprintf("the Client has the resource %q of %q at version %q or later", resource, service, version),
+			func(r *runner.Runner) error {
+				return r.ClientHasResourceOfServiceAtVersionOrLater(resource, service, version)
+			},
+		})
+	}
+	if !incremental && service != "LDS" && service != "CDS" {
+		return steps
+	}
+	for _, resource := range m.Gone() {
+		resource := resource
+		steps = append(steps, step{
+			fmt.Sprintf("the Client does not have the resource %q of %q", resource, service),
+			func(r *runner.Runner) error { return r.ClientDoesNotHaveResourceOfService(resource, service) },
+		})
+	}
+	return steps
+}
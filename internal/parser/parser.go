@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/cucumber/godog"
 	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
@@ -12,6 +14,7 @@ import (
 	"github.com/ii/xds-test-harness/internal/types"
 	"github.com/kylelemons/go-gypsy/yaml"
 	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 const (
@@ -77,6 +80,31 @@ func ResourceNames(res *envoy_service_discovery_v3.DiscoveryResponse) (resourceN
 	return resourceNames, err
 }
 
+// Turns a gherkin data table into a map per row, keyed by the table's header.
+// Headers are lowercased and cells have any surrounding quotes trimmed,
+// so tables can be written in the same style as our example tables.
+func TableToMaps(table *godog.Table) (rows []map[string]string, err error) {
+	if table == nil || len(table.Rows) == 0 {
+		return rows, err
+	}
+	header := []string{}
+	for _, cell := range table.Rows[0].Cells {
+		header = append(header, strings.ToLower(strings.TrimSpace(cell.Value)))
+	}
+	for i, row := range table.Rows[1:] {
+		if len(row.Cells) != len(header) {
+			err = fmt.Errorf("row %v of table has %v cells, but header has %v", i+1, len(row.Cells), len(header))
+			return nil, err
+		}
+		values := make(map[string]string)
+		for j, cell := range row.Cells {
+			values[header[j]] = strings.Trim(strings.TrimSpace(cell.Value), `"`)
+		}
+		rows = append(rows, values)
+	}
+	return rows, err
+}
+
 func ParseSupportedVariants(variants []string) (supported []types.Variant, err error) {
 	variantMap := map[string]types.Variant{
 		"sotw non-aggregated":        types.SotwNonAggregated,
@@ -96,21 +124,18 @@ func ParseSupportedVariants(variants []string) (supported []types.Variant, err e
 	return supported, err
 }
 
-func ValuesFromConfig(config string) (target, adapter, nodeID string, supportedVariants []types.Variant) {
+func ValuesFromConfig(config string) (target, adapter, nodeID string, supportedVariants []types.Variant, err error) {
 	c, err := yaml.ReadFile(config)
 	if err != nil {
-		log.Fatal().
-			Msgf("cannot read config: %v", config)
+		return target, adapter, nodeID, nil, fmt.Errorf("cannot read config: %v", config)
 	}
 	nodeID, err = c.Get("nodeID")
 	if err != nil {
-		log.Fatal().
-			Msgf("error reading config file for Node ID: %v\n", err)
+		return target, adapter, nodeID, nil, fmt.Errorf("error reading config file for Node ID: %v", err)
 	}
 	target, err = c.Get("targetAddress")
 	if err != nil {
-		log.Fatal().
-			Msgf("error reading config file for Target Address: %v\n", config)
+		return target, adapter, nodeID, nil, fmt.Errorf("error reading config file for Target Address: %v", config)
 	}
 	adapter, err = c.Get("adapterAddress")
 	if err != nil {
@@ -119,7 +144,7 @@ func ValuesFromConfig(config string) (target, adapter, nodeID string, supportedV
 	}
 	v, err := yaml.Child(c.Root, "variants")
 	if err != nil {
-		log.Fatal().Msgf("error getting variants from config: %v\n", err)
+		return target, adapter, nodeID, nil, fmt.Errorf("error getting variants from config: %v", err)
 	}
 	variants := []string{}
 	varsInYaml, ok := v.(yaml.List)
@@ -132,7 +157,112 @@ func ValuesFromConfig(config string) (target, adapter, nodeID string, supportedV
 	}
 	supportedVariants, err = ParseSupportedVariants(variants)
 	if err != nil {
-		log.Fatal().Msgf("Cannot parse supported variants from config: %v", err)
+		return target, adapter, nodeID, nil, fmt.Errorf("cannot parse supported variants from config: %v", err)
+	}
+	return target, adapter, nodeID, supportedVariants, nil
+}
+
+// Reads the optional node section of the config, giving the identity clients send
+// along with their node ID. Returns nil if the config has no node section.
+func NodeFromConfig(config string) (*core.Node, error) {
+	c, err := yaml.ReadFile(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read config: %v", err)
+	}
+	n, err := yaml.Child(c.Root, "node")
+	if _, missing := err.(*yaml.NodeNotFound); missing {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read node from config: %v", err)
+	}
+	return nodeFromYaml(n)
+}
+
+// Builds a node identity from yaml, such as the docstring of a step, for clients
+// that need to send more than their node ID. For example:
+//
+//	cluster: edge
+//	userAgentName: envoy
+//	userAgentVersion: 1.22.0
+//	locality:
+//	  region: us-east-1
+//	  zone: us-east-1a
+//	metadata:
+//	  team: payments
+//
+// The node ID is not part of it, as each client already has one.
+func ParseNode(text string) (*core.Node, error) {
+	n, err := yaml.Parse(strings.NewReader(text))
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse node identity: %v", err)
+	}
+	return nodeFromYaml(n)
+}
+
+func nodeFromYaml(n yaml.Node) (*core.Node, error) {
+	fields, ok := n.(yaml.Map)
+	if !ok {
+		return nil, fmt.Errorf("node identity should be a map of fields, got: %v", yaml.Render(n))
+	}
+	node := &core.Node{}
+	for key, value := range fields {
+		switch key {
+		case "cluster":
+			node.Cluster = yamlScalar(value)
+		case "userAgentName":
+			node.UserAgentName = yamlScalar(value)
+		case "userAgentVersion":
+			node.UserAgentVersionType = &core.Node_UserAgentVersion{UserAgentVersion: yamlScalar(value)}
+		case "locality":
+			locality, ok := value.(yaml.Map)
+			if !ok {
+				return nil, fmt.Errorf("node locality should be a map of region, zone, and subZone")
+			}
+			node.Locality = &core.Locality{
+				Region:  yamlScalar(locality["region"]),
+				Zone:    yamlScalar(locality["zone"]),
+				SubZone: yamlScalar(locality["subZone"]),
+			}
+		case "metadata":
+			values, ok := yamlToInterface(value).(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("node metadata should be a map")
+			}
+			metadata, err := structpb.NewStruct(values)
+			if err != nil {
+				return nil, fmt.Errorf("cannot convert node metadata: %v", err)
+			}
+			node.Metadata = metadata
+		default:
+			return nil, fmt.Errorf("unknown node field %v. Known fields are: cluster, userAgentName, userAgentVersion, locality, metadata", key)
+		}
+	}
+	return node, nil
+}
+
+func yamlScalar(n yaml.Node) string {
+	if scalar, ok := n.(yaml.Scalar); ok {
+		return strings.TrimSpace(scalar.String())
+	}
+	return ""
+}
+
+func yamlToInterface(n yaml.Node) interface{} {
+	switch v := n.(type) {
+	case yaml.Map:
+		m := make(map[string]interface{})
+		for key, value := range v {
+			m[key] = yamlToInterface(value)
+		}
+		return m
+	case yaml.List:
+		l := []interface{}{}
+		for i := 0; i < v.Len(); i++ {
+			l = append(l, yamlToInterface(v.Item(i)))
+		}
+		return l
+	default:
+		return yamlScalar(n)
 	}
-	return target, adapter, nodeID, supportedVariants
 }
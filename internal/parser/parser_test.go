@@ -3,6 +3,8 @@ package parser
 import (
 	"testing"
 
+	"github.com/cucumber/godog"
+	messages "github.com/cucumber/messages-go/v16"
 	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
@@ -200,7 +202,10 @@ func TestValuesFromConfig(t *testing.T) {
 		"adapter": "13000",
 	}
 	expectedVariants := []types.Variant{types.SotwNonAggregated, types.IncrementalAggregated}
-	target, adapter, nodeID, variants := ValuesFromConfig(config)
+	target, adapter, nodeID, variants, err := ValuesFromConfig(config)
+	if err != nil {
+		t.Fatalf("Could not read config: %v", err)
+	}
 	if target != expected["target"] {
 		t.Errorf("Target not parsed from config properly. expected: %v actual: %v", expected["target"], target)
 	}
@@ -216,3 +221,68 @@ func TestValuesFromConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestTableToMaps(t *testing.T) {
+	row := func(cells ...string) *messages.PickleTableRow {
+		r := &messages.PickleTableRow{}
+		for _, c := range cells {
+			r.Cells = append(r.Cells, &messages.PickleTableCell{Value: c})
+		}
+		return r
+	}
+	table := &godog.Table{Rows: []*messages.PickleTableRow{
+		row("Service", "resources", "version"),
+		row(`"CDS"`, `"A,B"`, `"1"`),
+		row("LDS", "C", "2"),
+	}}
+	rows, err := TableToMaps(table)
+	if err != nil {
+		t.Errorf("Error parsing table when expecting no err: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %v", len(rows))
+	}
+	if rows[0]["service"] != "CDS" || rows[0]["resources"] != "A,B" || rows[1]["version"] != "2" {
+		t.Errorf("Table not parsed into expected values: %v", rows)
+	}
+
+	table.Rows = append(table.Rows, row("EDS"))
+	if _, err := TableToMaps(table); err == nil {
+		t.Errorf("Parsing should return error when a row is missing cells. It did not.")
+	}
+}
+
+func TestParseNode(t *testing.T) {
+	yah := `
+cluster: edge
+userAgentName: envoy
+userAgentVersion: 1.22.0
+locality:
+  region: us-east-1
+  zone: us-east-1a
+metadata:
+  team: payments
+  labels:
+    tier: gold
+`
+	node, err := ParseNode(yah)
+	if err != nil {
+		t.Fatalf("Error parsing node when expecting no err: %v", err)
+	}
+	if node.Cluster != "edge" || node.UserAgentName != "envoy" || node.GetUserAgentVersion() != "1.22.0" {
+		t.Errorf("Node fields not parsed properly: %v", node)
+	}
+	if node.Locality.Region != "us-east-1" || node.Locality.Zone != "us-east-1a" || node.Locality.SubZone != "" {
+		t.Errorf("Node locality not parsed properly: %v", node.Locality)
+	}
+	team := node.Metadata.Fields["team"].GetStringValue()
+	tier := node.Metadata.Fields["labels"].GetStructValue().Fields["tier"].GetStringValue()
+	if team != "payments" || tier != "gold" {
+		t.Errorf("Node metadata not parsed properly: %v", node.Metadata)
+	}
+
+	nah := "clutser: edge"
+	if _, err := ParseNode(nah); err == nil {
+		t.Errorf("Parsing should return error for unknown node fields. It did not.")
+	}
+}
@@ -0,0 +1,141 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ii/xds-test-harness/internal/types"
+)
+
+// How a scenario's result changed between two runs. Before or After is empty when
+// the scenario is only in one of them.
+type ScenarioChange struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Feature string `json:"feature"`
+	Line    int    `json:"line"`
+	Example string `json:"example,omitempty"`
+	Before  string `json:"before,omitempty"`
+	After   string `json:"after,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type VariantDiff struct {
+	Variant     string           `json:"variant"`
+	Regressions []ScenarioChange `json:"regressions"`
+	Fixes       []ScenarioChange `json:"fixes"`
+	Added       []ScenarioChange `json:"added"`
+	Removed     []ScenarioChange `json:"removed"`
+}
+
+type ResultsDiff struct {
+	Variants []VariantDiff `json:"variants"`
+}
+
+// Compares two runs, scenario by scenario. A regression is a scenario that failed
+// in the later run but not the earlier one, and a fix is one that failed before and
+// passes now. Undefined scenarios count as failed, as they do for the exit code.
+func Diff(before, after types.Results) ResultsDiff {
+	beforeByVariant := map[string]types.VariantResults{}
+	for _, variant := range before.ResultsByVariant {
+		beforeByVariant[variant.Name] = variant
+	}
+
+	diff := ResultsDiff{}
+	seen := map[string]bool{}
+	for _, variant := range after.ResultsByVariant {
+		seen[variant.Name] = true
+		diff.Variants = append(diff.Variants, diffVariant(beforeByVariant[variant.Name], variant))
+	}
+	for _, variant := range before.ResultsByVariant {
+		if !seen[variant.Name] {
+			diff.Variants = append(diff.Variants, diffVariant(variant, types.VariantResults{Name: variant.Name}))
+		}
+	}
+	return diff
+}
+
+func diffVariant(before, after types.VariantResults) VariantDiff {
+	diff := VariantDiff{Variant: after.Name}
+	previous := map[string]types.ScenarioResult{}
+	for _, scenario := range before.Scenarios {
+		previous[scenario.ScenarioID()] = scenario
+	}
+	current := map[string]bool{}
+	for _, scenario := range after.Scenarios {
+		id := scenario.ScenarioID()
+		current[id] = true
+		old, ok := previous[id]
+		change := scenarioChange(scenario)
+		change.Before = old.Status
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, change)
+		case types.Failing(scenario.Status) && !types.Failing(old.Status):
+			diff.Regressions = append(diff.Regressions, change)
+		case scenario.Status == "passed" && types.Failing(old.Status):
+			diff.Fixes = append(diff.Fixes, change)
+		}
+	}
+	for _, scenario := range before.Scenarios {
+		if !current[scenario.ScenarioID()] {
+			change := scenarioChange(scenario)
+			change.Before, change.After, change.Error = scenario.Status, "", ""
+			diff.Removed = append(diff.Removed, change)
+		}
+	}
+	return diff
+}
+
+func scenarioChange(scenario types.ScenarioResult) ScenarioChange {
+	return ScenarioChange{
+		ID:      scenario.ScenarioID(),
+		Name:    scenario.Name,
+		Feature: scenario.Feature,
+		Line:    scenario.Line,
+		Example: scenario.Example,
+		After:   scenario.Status,
+		Error:   scenario.Error,
+	}
+}
+
+func (d ResultsDiff) Regressed() bool {
+	for _, variant := range d.Variants {
+		if len(variant.Regressions) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (d ResultsDiff) Text() string {
+	divider := "-------------------"
+	var b strings.Builder
+	for _, variant := range d.Variants {
+		b.WriteString(variant.Variant + "\n" + divider + "\n")
+		fmt.Fprintf(&b, "Regressions: %v, Fixes: %v, New: %v, Removed: %v\n",
+			len(variant.Regressions), len(variant.Fixes), len(variant.Added), len(variant.Removed))
+		writeChanges(&b, "Regressions", variant.Regressions)
+		writeChanges(&b, "Fixes", variant.Fixes)
+		writeChanges(&b, "New scenarios", variant.Added)
+		writeChanges(&b, "Removed scenarios", variant.Removed)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func writeChanges(b *strings.Builder, title string, changes []ScenarioChange) {
+	if len(changes) == 0 {
+		return
+	}
+	b.WriteString(title + ":\n")
+	for _, change := range changes {
+		fmt.Fprintf(b, "  - %v\n", change.ID)
+		if change.Before != "" && change.After != "" {
+			fmt.Fprintf(b, "    %v -> %v\n", change.Before, change.After)
+		}
+		if change.Error != "" {
+			fmt.Fprintf(b, "    Error: %v\n", change.Error)
+		}
+	}
+}
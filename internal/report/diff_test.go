@@ -0,0 +1,101 @@
+package report
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ii/xds-test-harness/internal/types"
+)
+
+func TestDiff(t *testing.T) {
+	before := types.Results{
+		ResultsByVariant: []types.VariantResults{
+			{
+				Name: "sotw non-aggregated",
+				Scenarios: []types.ScenarioResult{
+					{Name: "[CDS] Wildcard", Feature: "features/subscribing.feature", Status: "passed"},
+					{Name: "[LDS] Versions", Feature: "features/subscribing.feature", Example: "| LDS | 1 |", Status: "failed"},
+					{Name: "[RDS] Gone", Feature: "features/subscribing.feature", Status: "passed"},
+				},
+			},
+			{
+				Name:      "sotw aggregated",
+				Scenarios: []types.ScenarioResult{{Name: "[CDS] Wildcard", Feature: "features/subscribing.feature", Status: "passed"}},
+			},
+		},
+	}
+	after := types.Results{
+		ResultsByVariant: []types.VariantResults{
+			{
+				Name: "sotw non-aggregated",
+				Scenarios: []types.ScenarioResult{
+					{ID: "features/subscribing.feature:[CDS] Wildcard", Name: "[CDS] Wildcard", Feature: "features/subscribing.feature", Status: "failed", Error: "wrong version"},
+					{Name: "[LDS] Versions", Feature: "features/subscribing.feature", Example: "| LDS | 1 |", Status: "passed"},
+					{Name: "[EDS] New", Feature: "features/subscribing.feature", Status: "passed"},
+				},
+			},
+		},
+	}
+
+	diff := Diff(before, after)
+	if len(diff.Variants) != 2 {
+		t.Fatalf("Expected a diff for both variants, got %v", len(diff.Variants))
+	}
+	sotw := diff.Variants[0]
+	if len(sotw.Regressions) != 1 || sotw.Regressions[0].ID != "features/subscribing.feature:[CDS] Wildcard" || sotw.Regressions[0].Before != "passed" {
+		t.Errorf("Regressions not as expected: %+v", sotw.Regressions)
+	}
+	if len(sotw.Fixes) != 1 || sotw.Fixes[0].ID != "features/subscribing.feature:[LDS] Versions | LDS | 1 |" {
+		t.Errorf("Fixes not as expected: %+v", sotw.Fixes)
+	}
+	if len(sotw.Added) != 1 || sotw.Added[0].Name != "[EDS] New" {
+		t.Errorf("New scenarios not as expected: %+v", sotw.Added)
+	}
+	if len(sotw.Removed) != 1 || sotw.Removed[0].Name != "[RDS] Gone" {
+		t.Errorf("Removed scenarios not as expected: %+v", sotw.Removed)
+	}
+	if removed := diff.Variants[1]; removed.Variant != "sotw aggregated" || len(removed.Removed) != 1 {
+		t.Errorf("Variant missing from the later run should have its scenarios removed: %+v", removed)
+	}
+	if !diff.Regressed() {
+		t.Errorf("Expected the diff to have regressed")
+	}
+	if text := diff.Text(); !strings.Contains(text, "Regressions: 1, Fixes: 1, New: 1, Removed: 1") {
+		t.Errorf("Text summary not as expected:\n%v", text)
+	}
+}
+
+func TestDiffCountsUndefinedAsFailed(t *testing.T) {
+	results := func(status string) types.Results {
+		return types.Results{ResultsByVariant: []types.VariantResults{{
+			Name:      "sotw non-aggregated",
+			Scenarios: []types.ScenarioResult{{Name: "[CDS] Typo", Feature: "features/subscribing.feature", Status: status}},
+		}}}
+	}
+	if diff := Diff(results("passed"), results("undefined")); len(diff.Variants[0].Regressions) != 1 {
+		t.Errorf("Passed to undefined should be a regression: %+v", diff.Variants[0])
+	}
+	if diff := Diff(results("undefined"), results("passed")); len(diff.Variants[0].Fixes) != 1 {
+		t.Errorf("Undefined to passed should be a fix: %+v", diff.Variants[0])
+	}
+	if diff := Diff(results("passed"), results("pending")); diff.Regressed() {
+		t.Errorf("Passed to pending shouldn't be a regression: %+v", diff.Variants[0])
+	}
+}
+
+func TestReadResultsFromBeforeScenarioLines(t *testing.T) {
+	old := `{"ResultsByVariant": [{"name": "sotw non-aggregated", "failedScenarios": [
+		{"name": "[CDS] Wildcard", "failedStep": "the Client receives", "line": "features/subscribing.feature:42", "error": "timeout"}]}]}`
+	var results types.Results
+	if err := json.Unmarshal([]byte(old), &results); err != nil {
+		t.Fatalf("Cannot read results from before scenario lines: %v", err)
+	}
+	failure := results.ResultsByVariant[0].FailedScenarios[0]
+	if failure.Feature != "features/subscribing.feature" || failure.FailedStepLine != 42 || failure.Line != 0 {
+		t.Errorf("Old line not read as the failed step's: %+v", failure)
+	}
+	if failure.ID != "" || failure.Error != "timeout" {
+		t.Errorf("Other fields not read as expected: %+v", failure)
+	}
+}
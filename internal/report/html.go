@@ -0,0 +1,168 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+
+	"github.com/ii/xds-test-harness/internal/types"
+)
+
+// The services a scenario covers, taken from the bracketed start of its name,
+// like [CDS] or ["LDS","RDS"]. Scenarios without one are counted under "other".
+func scenarioServices(name string) []string {
+	if !strings.HasPrefix(name, "[") || !strings.Contains(name, "]") {
+		return []string{"other"}
+	}
+	inside := name[1:strings.Index(name, "]")]
+	services := []string{}
+	for _, service := range strings.Split(inside, ",") {
+		service = strings.ToUpper(strings.Trim(strings.TrimSpace(service), `"`))
+		if service != "" {
+			services = append(services, service)
+		}
+	}
+	if len(services) == 0 {
+		return []string{"other"}
+	}
+	return services
+}
+
+type htmlCell struct {
+	Passed int
+	Total  int
+	Status string
+}
+
+type htmlRow struct {
+	Service string
+	Cells   []htmlCell
+}
+
+type htmlReport struct {
+	Results  types.Results
+	Variants []string
+	Matrix   []htmlRow
+}
+
+// Builds a single, self-contained html page of the results, with a matrix of
+// services against variants and the steps of every scenario.
+func HTML(results types.Results) ([]byte, error) {
+	report := htmlReport{Results: results}
+	cells := map[string]map[string]*htmlCell{}
+	for _, variant := range results.ResultsByVariant {
+		report.Variants = append(report.Variants, variant.Name)
+		for _, scenario := range variant.Scenarios {
+			for _, service := range scenarioServices(scenario.Name) {
+				if cells[service] == nil {
+					cells[service] = map[string]*htmlCell{}
+				}
+				cell := cells[service][variant.Name]
+				if cell == nil {
+					cell = &htmlCell{Status: "passed"}
+					cells[service][variant.Name] = cell
+				}
+				cell.Total++
+				switch {
+				case scenario.Status == "passed":
+					cell.Passed++
+				case scenario.Status == "failed":
+					cell.Status = "failed"
+				case cell.Status != "failed":
+					cell.Status = "incomplete"
+				}
+			}
+		}
+	}
+	services := []string{}
+	for service := range cells {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	for _, service := range services {
+		row := htmlRow{Service: service}
+		for _, variant := range report.Variants {
+			cell := cells[service][variant]
+			if cell == nil {
+				cell = &htmlCell{Status: "none"}
+			}
+			row.Cells = append(row.Cells, *cell)
+		}
+		report.Matrix = append(report.Matrix, row)
+	}
+
+	var b bytes.Buffer
+	if err := htmlTemplate.Execute(&b, report); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"percent": func(rate float64) string { return fmt.Sprintf("%.0f%%", rate*100) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>xDS Conformance Report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.8em; text-align: left; }
+.passed { background: #d4f4d4; }
+.failed { background: #f8d0d0; }
+.incomplete, .skipped, .pending, .undefined { background: #f8f0c8; }
+.none { background: #eee; color: #888; }
+details { margin: 0.3em 0; padding: 0.3em; border-left: 4px solid #ccc; }
+details.passed { border-color: #4a4; background: none; }
+details.failed { border-color: #c44; background: none; }
+ol { margin: 0.3em 0; }
+pre { white-space: pre-wrap; background: #f6f6f6; padding: 0.5em; }
+.location { color: #666; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>xDS Conformance Report</h1>
+<p>Ran {{.Results.Total}} scenarios across {{len .Results.Variants}} variants:
+{{.Results.Passed}} passed, {{.Results.Failed}} failed, {{.Results.Skipped}} skipped,
+{{.Results.Undefined}} undefined and {{.Results.Pending}} pending.</p>
+
+<h2>Services by variant</h2>
+<table>
+<tr><th>Service</th>{{range .Variants}}<th>{{.}}</th>{{end}}</tr>
+{{range .Matrix}}<tr><th>{{.Service}}</th>{{range .Cells}}<td class="{{.Status}}">{{if .Total}}{{.Passed}}/{{.Total}}{{else}}-{{end}}</td>{{end}}</tr>
+{{end}}</table>
+
+{{with .Results.Coverage}}
+<h2>Spec coverage</h2>
+<table>
+<tr><th>Level</th><th>Scenarios</th><th>Passed</th><th>Pass rate</th></tr>
+{{range .Levels}}{{if .Scenarios}}<tr><th>{{.Level}}</th><td>{{.Scenarios}}</td><td>{{.Passed}}</td><td>{{percent .PassRate}}</td></tr>
+{{end}}{{end}}</table>
+<table>
+<tr><th>Requirement</th><th>Level</th><th>Section</th><th>Passed</th></tr>
+{{range .Requirements}}<tr><th title="{{.Description}}">{{.ID}}</th><td>{{or .Level "not in requirements list"}}</td><td>{{.Section}}</td><td class="{{if .Failed}}failed{{else if eq .Passed .Scenarios}}passed{{else}}incomplete{{end}}">{{.Passed}}/{{.Scenarios}}</td></tr>
+{{end}}{{range .Uncovered}}<tr><th title="{{.Description}}">{{.ID}}</th><td>{{.Level}}</td><td>{{.Section}}</td><td class="none">no scenarios</td></tr>
+{{end}}</table>
+{{end}}
+
+{{range .Results.ResultsByVariant}}
+<h2>{{.Name}}</h2>
+<p>{{.Passed}} of {{.Total}} scenarios passed.</p>
+{{with .PushLatency}}<p>Push latency over {{.Samples}} deliveries: p50 {{.P50}}, p90 {{.P90}}, p99 {{.P99}}, max {{.Max}}.</p>{{end}}
+{{range .Scenarios}}
+<details class="{{.Status}}"{{if eq .Status "failed"}} open{{end}}>
+<summary><span class="{{.Status}}">{{.Status}}</span> {{.Name}} {{.Example}}</summary>
+<div class="location">{{.Feature}}:{{.Line}}{{with .PushLatency}} &middot; push latency p50 {{.P50}}, max {{.Max}}{{end}}{{with .SnapshotDelivery}} &middot; state arrived in {{.}}{{end}}</div>
+<ol>
+{{range .Steps}}<li class="{{.Status}}">{{.Keyword}} {{.Text}}{{if .Error}}<pre>{{.Error}}</pre>{{end}}</li>
+{{end}}</ol>
+{{if and .Error (not .Steps)}}<pre>{{.Error}}</pre>{{end}}
+</details>
+{{end}}
+{{end}}
+</body>
+</html>
+`))
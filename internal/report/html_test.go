@@ -0,0 +1,70 @@
+package report
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ii/xds-test-harness/internal/types"
+)
+
+func TestScenarioServices(t *testing.T) {
+	tests := map[string][]string{
+		`["CDS"] Wildcard`:           {"CDS"},
+		`["LDS","RDS"] ADS`:          {"LDS", "RDS"},
+		`[eds] Lowercase`:            {"EDS"},
+		`Restarting without a [CDS]`: {"other"},
+	}
+	for name, expected := range tests {
+		actual := scenarioServices(name)
+		if strings.Join(actual, ",") != strings.Join(expected, ",") {
+			t.Errorf("Services not parsed from %q. expected: %v, actual: %v", name, expected, actual)
+		}
+	}
+}
+
+func TestHTML(t *testing.T) {
+	results := types.Results{
+		Total:    2,
+		Passed:   1,
+		Failed:   1,
+		Variants: []string{"sotw non-aggregated"},
+		ResultsByVariant: []types.VariantResults{
+			{
+				Name: "sotw non-aggregated",
+				Scenarios: []types.ScenarioResult{
+					{Name: `["CDS"] Wildcard`, Feature: "features/subscribing.feature", Line: 18, Status: "passed"},
+					{Name: `["LDS"] Wildcard`, Feature: "features/subscribing.feature", Line: 19, Status: "failed",
+						Steps: []types.StepResult{
+							{Keyword: "Given", Text: "a target setup with <script>", Status: "passed"},
+							{Keyword: "Then", Text: "the Client receives", Status: "failed", Error: "wrong version"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	results.Coverage = &types.Coverage{
+		Requirements: []types.RequirementCoverage{{ID: "wildcard", Level: "must", Scenarios: 2, Passed: 1, Failed: 1}},
+		Uncovered:    []types.RequirementCoverage{{ID: "stale-nonce", Level: "should"}},
+		Levels:       []types.LevelCoverage{{Level: "must", Scenarios: 2, Passed: 1, PassRate: 0.5}},
+	}
+
+	data, err := HTML(results)
+	if err != nil {
+		t.Fatalf("Error building html report when expecting no err: %v", err)
+	}
+	page := string(data)
+	for _, expected := range []string{
+		`<th>CDS</th><td class="passed">1/1</td>`,
+		`<th>LDS</th><td class="failed">0/1</td>`,
+		`Then the Client receives<pre>wrong version</pre>`,
+		`a target setup with &lt;script&gt;`,
+		`<th>must</th><td>2</td><td>1</td><td>50%</td>`,
+		`<td class="none">no scenarios</td>`,
+	} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("Expected report to contain %q", expected)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Reports built from the results of a run, for tools other than our own.
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ii/xds-test-harness/internal/types"
+)
+
+type junitTestSuites struct {
+	XMLName    xml.Name          `xml:"testsuites"`
+	Name       string            `xml:"name,attr"`
+	Tests      int               `xml:"tests,attr"`
+	Failures   int               `xml:"failures,attr"`
+	Errors     int               `xml:"errors,attr"`
+	Skipped    int               `xml:"skipped,attr"`
+	Time       string            `xml:"time,attr"`
+	TestSuites []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string           `xml:"name,attr"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	Errors    int              `xml:"errors,attr"`
+	Skipped   int              `xml:"skipped,attr"`
+	Time      string           `xml:"time,attr"`
+	TestCases []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// Builds a JUnit XML report of the results, with a testsuite per variant
+// and a testcase per scenario, or per example of a scenario outline.
+// Failed scenarios are failures, and undefined ones are errors, since they
+// point to a problem with the suite rather than the target. Pending ones are
+// skipped, as the adapter left out an optional call they need.
+func JUnit(results types.Results) ([]byte, error) {
+	report := junitTestSuites{Name: "xDS Conformance"}
+	var total time.Duration
+
+	for _, variant := range results.ResultsByVariant {
+		suite := &junitTestSuite{Name: variant.Name}
+		var elapsed time.Duration
+
+		// outline examples share a name, so number them as godog does.
+		names := make(map[string]int)
+		for _, scenario := range variant.Scenarios {
+			names[scenario.Name]++
+		}
+		seen := make(map[string]int)
+
+		for _, scenario := range variant.Scenarios {
+			name := scenario.Name
+			if names[name] > 1 {
+				seen[name]++
+				name = fmt.Sprintf("%v #%v", name, seen[name])
+			}
+			tc := &junitTestCase{
+				Name:      name,
+				Classname: variant.Name + "." + featureName(scenario.Feature),
+				File:      fmt.Sprintf("%v:%v", scenario.Feature, scenario.Line),
+				Time:      seconds(scenario.Duration),
+			}
+			switch scenario.Status {
+			case "failed":
+				tc.Failure = &junitFailure{
+					Message: "Step " + scenario.FailedStep + ": " + scenario.Error,
+					Type:    "failed",
+					Text:    scenario.Error,
+				}
+				suite.Failures++
+			case "undefined":
+				tc.Error = &junitFailure{
+					Message: "scenario has undefined steps",
+					Type:    scenario.Status,
+				}
+				suite.Errors++
+			case "pending":
+				tc.Skipped = &junitSkipped{Message: "scenario has pending steps"}
+				suite.Skipped++
+			case "skipped":
+				tc.Skipped = &junitSkipped{}
+				suite.Skipped++
+			}
+			suite.Tests++
+			elapsed += scenario.Duration
+			suite.TestCases = append(suite.TestCases, tc)
+		}
+		suite.Time = seconds(elapsed)
+
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Skipped += suite.Skipped
+		total += elapsed
+		report.TestSuites = append(report.TestSuites, suite)
+	}
+	report.Time = seconds(total)
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal junit report: %v", err)
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// features/delta.feature => delta
+func featureName(uri string) string {
+	name := uri[strings.LastIndex(uri, "/")+1:]
+	return strings.TrimSuffix(name, ".feature")
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
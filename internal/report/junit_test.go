@@ -0,0 +1,57 @@
+package report
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/ii/xds-test-harness/internal/types"
+)
+
+func TestJUnit(t *testing.T) {
+	results := types.Results{
+		ResultsByVariant: []types.VariantResults{
+			{
+				Name: "sotw non-aggregated",
+				Scenarios: []types.ScenarioResult{
+					{Name: "[CDS] Wildcard", Feature: "features/subscribing.feature", Line: 18, Status: "passed", Duration: 2 * time.Second},
+					{Name: "[CDS] Wildcard", Feature: "features/subscribing.feature", Line: 19, Status: "failed", FailedStep: "the Client receives", Error: "wrong version", Duration: time.Second},
+				},
+			},
+			{
+				Name: "incremental aggregated",
+				Scenarios: []types.ScenarioResult{
+					{Name: "[LDS] Subscribe", Feature: "features/delta.feature", Line: 16, Status: "undefined"},
+				},
+			},
+		},
+	}
+
+	data, err := JUnit(results)
+	if err != nil {
+		t.Fatalf("Error building junit report when expecting no err: %v", err)
+	}
+	var report junitTestSuites
+	if err := xml.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Report is not valid xml: %v", err)
+	}
+
+	if report.Tests != 3 || report.Failures != 1 || report.Errors != 1 || len(report.TestSuites) != 2 {
+		t.Errorf("Report totals not as expected. tests: %v, failures: %v, errors: %v, suites: %v",
+			report.Tests, report.Failures, report.Errors, len(report.TestSuites))
+	}
+	sotw := report.TestSuites[0]
+	if sotw.Name != "sotw non-aggregated" || sotw.Time != "3.000" {
+		t.Errorf("Testsuite not built from variant as expected: %v, time %v", sotw.Name, sotw.Time)
+	}
+	if sotw.TestCases[0].Name != "[CDS] Wildcard #1" || sotw.TestCases[1].Name != "[CDS] Wildcard #2" {
+		t.Errorf("Outline examples not numbered: %v, %v", sotw.TestCases[0].Name, sotw.TestCases[1].Name)
+	}
+	failed := sotw.TestCases[1]
+	if failed.Failure == nil || failed.Failure.Text != "wrong version" || failed.File != "features/subscribing.feature:19" {
+		t.Errorf("Failed scenario not reported as expected: %+v", failed)
+	}
+	if sotw.TestCases[0].Classname != "sotw non-aggregated.subscribing" {
+		t.Errorf("Unexpected classname: %v", sotw.TestCases[0].Classname)
+	}
+}
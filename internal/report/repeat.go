@@ -0,0 +1,57 @@
+package report
+
+import "github.com/ii/xds-test-harness/internal/types"
+
+// Merges repeated runs of a variant into one result, with every run of every
+// scenario, and how often each scenario passed.
+func MergeRepeats(runs []types.VariantResults) types.VariantResults {
+	merged := types.VariantResults{}
+	if len(runs) == 0 {
+		return merged
+	}
+	merged.Name = runs[0].Name
+	rates := map[string]*types.PassRate{}
+	order := []string{}
+	for _, run := range runs {
+		merged.Total += run.Total
+		merged.Passed += run.Passed
+		merged.Failed += run.Failed
+		merged.Skipped += run.Skipped
+		merged.Undefined += run.Undefined
+		merged.Pending += run.Pending
+		merged.Steps.Total += run.Steps.Total
+		merged.Steps.Passed += run.Steps.Passed
+		merged.Steps.Failed += run.Steps.Failed
+		merged.Steps.Skipped += run.Steps.Skipped
+		merged.Steps.Undefined += run.Steps.Undefined
+		merged.Steps.Pending += run.Steps.Pending
+		merged.FailedScenarios = append(merged.FailedScenarios, run.FailedScenarios...)
+		merged.Scenarios = append(merged.Scenarios, run.Scenarios...)
+		merged.PushLatency = types.MergePushLatency(merged.PushLatency, run.PushLatency)
+		for _, scenario := range run.Scenarios {
+			id := scenario.ScenarioID()
+			rate, ok := rates[id]
+			if !ok {
+				rate = &types.PassRate{
+					ID:      id,
+					Name:    scenario.Name,
+					Feature: scenario.Feature,
+					Line:    scenario.Line,
+					Example: scenario.Example,
+				}
+				rates[id] = rate
+				order = append(order, id)
+			}
+			rate.Runs++
+			if scenario.Status == "passed" {
+				rate.Passed++
+			}
+		}
+	}
+	for _, id := range order {
+		rate := rates[id]
+		rate.Ratio = float64(rate.Passed) / float64(rate.Runs)
+		merged.PassRates = append(merged.PassRates, *rate)
+	}
+	return merged
+}
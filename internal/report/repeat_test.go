@@ -0,0 +1,42 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/ii/xds-test-harness/internal/types"
+)
+
+func TestMergeRepeats(t *testing.T) {
+	run := func(cds, lds string) types.VariantResults {
+		results := types.VariantResults{Name: "sotw aggregated", Total: 2}
+		for _, s := range []types.ScenarioResult{{Name: "[CDS] Wildcard", Status: cds}, {Name: "[LDS] Wildcard", Status: lds}} {
+			if s.Status == "passed" {
+				results.Passed++
+			} else {
+				results.Failed++
+			}
+			results.Scenarios = append(results.Scenarios, s)
+		}
+		return results
+	}
+
+	merged := MergeRepeats([]types.VariantResults{
+		run("passed", "failed"),
+		run("failed", "failed"),
+		run("passed", "failed"),
+		run("passed", "failed"),
+	})
+	if merged.Name != "sotw aggregated" || merged.Total != 8 || merged.Passed != 3 || len(merged.Scenarios) != 8 {
+		t.Errorf("Runs not merged as expected: %v total, %v passed, %v scenarios", merged.Total, merged.Passed, len(merged.Scenarios))
+	}
+	if len(merged.PassRates) != 2 {
+		t.Fatalf("Expected a pass rate for each scenario, got %v", merged.PassRates)
+	}
+	cds, lds := merged.PassRates[0], merged.PassRates[1]
+	if cds.Name != "[CDS] Wildcard" || cds.Runs != 4 || cds.Passed != 3 || cds.Ratio != 0.75 || !cds.Flaky() {
+		t.Errorf("CDS pass rate not as expected: %+v", cds)
+	}
+	if lds.Passed != 0 || lds.Flaky() {
+		t.Errorf("LDS failed every run, so should not be flaky: %+v", lds)
+	}
+}
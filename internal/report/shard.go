@@ -0,0 +1,144 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/ii/xds-test-harness/internal/types"
+)
+
+// Merges the results of the shards of a run into the results the run would have
+// had unsharded. Each shard's scenarios are put back in the order of the features,
+// and when scenarios were repeated, in the order of their runs.
+func MergeShards(shards []types.Results) types.Results {
+	byName := map[string]*types.VariantResults{}
+	order := []string{}
+	scenarioRuns := map[string][]int{}
+	for _, shard := range shards {
+		for _, variant := range shard.ResultsByVariant {
+			merged, ok := byName[variant.Name]
+			if !ok {
+				merged = &types.VariantResults{Name: variant.Name}
+				byName[variant.Name] = merged
+				order = append(order, variant.Name)
+			}
+			merged.Steps = addSteps(merged.Steps, variant.Steps)
+			// a scenario shows up once for every time it was run, so the nth time
+			// we see it in a shard is its nth run.
+			seen := map[string]int{}
+			for _, scenario := range variant.Scenarios {
+				id := scenario.ScenarioID()
+				merged.Scenarios = append(merged.Scenarios, scenario)
+				scenarioRuns[variant.Name] = append(scenarioRuns[variant.Name], seen[id])
+				seen[id]++
+			}
+			merged.PassRates = append(merged.PassRates, variant.PassRates...)
+			merged.PushLatency = types.MergePushLatency(merged.PushLatency, variant.PushLatency)
+		}
+	}
+
+	results := types.Results{}
+	for _, name := range order {
+		merged := byName[name]
+		sortScenarios(merged.Scenarios, scenarioRuns[name])
+		sort.SliceStable(merged.PassRates, func(i, j int) bool {
+			a, b := merged.PassRates[i], merged.PassRates[j]
+			if a.Feature != b.Feature {
+				return a.Feature < b.Feature
+			}
+			return a.Line < b.Line
+		})
+		for _, scenario := range merged.Scenarios {
+			merged.Total++
+			switch scenario.Status {
+			case "passed":
+				merged.Passed++
+			case "failed":
+				merged.Failed++
+				merged.FailedScenarios = append(merged.FailedScenarios, scenario.Failure())
+			case "skipped":
+				merged.Skipped++
+			case "undefined":
+				merged.Undefined++
+			case "pending":
+				merged.Pending++
+			}
+		}
+		results.Total += int64(merged.Total)
+		results.Passed += int64(merged.Passed)
+		results.Failed += int64(merged.Failed)
+		results.Skipped += int64(merged.Skipped)
+		results.Undefined += int64(merged.Undefined)
+		results.Pending += int64(merged.Pending)
+		results.Steps = addSteps(results.Steps, merged.Steps)
+		results.PushLatency = types.MergePushLatency(results.PushLatency, merged.PushLatency)
+		results.Variants = append(results.Variants, name)
+		results.ResultsByVariant = append(results.ResultsByVariant, *merged)
+	}
+	return results
+}
+
+func sortScenarios(scenarios []types.ScenarioResult, runs []int) {
+	index := make([]int, len(scenarios))
+	for i := range index {
+		index[i] = i
+	}
+	sort.SliceStable(index, func(i, j int) bool {
+		a, b := scenarios[index[i]], scenarios[index[j]]
+		if runs[index[i]] != runs[index[j]] {
+			return runs[index[i]] < runs[index[j]]
+		}
+		if a.Feature != b.Feature {
+			return a.Feature < b.Feature
+		}
+		return a.Line < b.Line
+	})
+	sorted := make([]types.ScenarioResult, len(scenarios))
+	for i, from := range index {
+		sorted[i] = scenarios[from]
+	}
+	copy(scenarios, sorted)
+}
+
+func addSteps(a, b types.StepCounts) types.StepCounts {
+	return types.StepCounts{
+		Total:     a.Total + b.Total,
+		Passed:    a.Passed + b.Passed,
+		Failed:    a.Failed + b.Failed,
+		Skipped:   a.Skipped + b.Skipped,
+		Undefined: a.Undefined + b.Undefined,
+		Pending:   a.Pending + b.Pending,
+	}
+}
+
+// Merges the cucumber json reports of the shards of a variant into one. Features
+// are matched by their id, as each shard ran from a directory of its own.
+func MergeCucumber(reports [][]byte) ([]byte, error) {
+	byID := map[string]*types.CukeFeatureJSON{}
+	for _, report := range reports {
+		features := []types.CukeFeatureJSON{}
+		if err := json.Unmarshal(report, &features); err != nil {
+			return nil, fmt.Errorf("cannot parse cucumber report: %v", err)
+		}
+		for i := range features {
+			feature := features[i]
+			merged, ok := byID[feature.ID]
+			if !ok {
+				byID[feature.ID] = &feature
+				continue
+			}
+			merged.Elements = append(merged.Elements, feature.Elements...)
+		}
+	}
+
+	merged := []types.CukeFeatureJSON{}
+	for _, feature := range byID {
+		elements := feature.Elements
+		sort.SliceStable(elements, func(i, j int) bool { return elements[i].Line < elements[j].Line })
+		merged = append(merged, *feature)
+	}
+	// godog orders features by name.
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Name < merged[j].Name })
+	return json.MarshalIndent(merged, "", "    ")
+}
@@ -0,0 +1,120 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ii/xds-test-harness/internal/types"
+)
+
+func TestMergeShards(t *testing.T) {
+	first := types.Results{ResultsByVariant: []types.VariantResults{{
+		Name:  "sotw aggregated",
+		Steps: types.StepCounts{Total: 4, Passed: 3, Failed: 1},
+		Scenarios: []types.ScenarioResult{
+			{Name: "A", Feature: "features/a.feature", Line: 10, Status: "passed"},
+			{Name: "B", Feature: "features/b.feature", Line: 5, Status: "failed", Error: "wrong version"},
+		},
+	}}}
+	second := types.Results{ResultsByVariant: []types.VariantResults{{
+		Name:  "sotw aggregated",
+		Steps: types.StepCounts{Total: 2, Passed: 2},
+		Scenarios: []types.ScenarioResult{
+			{Name: "A", Feature: "features/a.feature", Line: 11, Status: "passed"},
+		},
+	}}}
+
+	merged := MergeShards([]types.Results{first, second})
+	if merged.Total != 3 || merged.Passed != 2 || merged.Failed != 1 || merged.Steps.Total != 6 {
+		t.Errorf("Merged counts not as expected: %+v", merged)
+	}
+	if len(merged.Variants) != 1 {
+		t.Fatalf("Expected the shards' variants merged into one, got: %v", merged.Variants)
+	}
+	scenarios := merged.ResultsByVariant[0].Scenarios
+	lines := []int{scenarios[0].Line, scenarios[1].Line, scenarios[2].Line}
+	if lines[0] != 10 || lines[1] != 11 || lines[2] != 5 {
+		t.Errorf("Scenarios not put back in feature order: %v", lines)
+	}
+	failed := merged.ResultsByVariant[0].FailedScenarios
+	if len(failed) != 1 || failed[0].Error != "wrong version" {
+		t.Errorf("Failed scenarios not gathered: %+v", failed)
+	}
+}
+
+func TestMergeShardsKeepsRepeatsInRunOrder(t *testing.T) {
+	shard := func(lines ...int) types.Results {
+		variant := types.VariantResults{Name: "sotw aggregated"}
+		for _, line := range lines {
+			name := fmt.Sprintf("Scenario at %v", line)
+			variant.Scenarios = append(variant.Scenarios, types.ScenarioResult{Name: name, Feature: "features/a.feature", Line: line, Status: "passed"})
+		}
+		return types.Results{ResultsByVariant: []types.VariantResults{variant}}
+	}
+	// each shard ran its scenarios twice.
+	merged := MergeShards([]types.Results{shard(10, 12, 10, 12), shard(11, 11)})
+	lines := []int{}
+	for _, scenario := range merged.ResultsByVariant[0].Scenarios {
+		lines = append(lines, scenario.Line)
+	}
+	expected := []int{10, 11, 12, 10, 11, 12}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Fatalf("Repeated scenarios not in run order. expected: %v, actual: %v", expected, lines)
+		}
+	}
+}
+
+func TestMergeCucumber(t *testing.T) {
+	first := `[{"uri": "/tmp/a/x.feature", "id": "x", "name": "X", "line": 1, "elements": [{"id": "x;one", "line": 9}]},
+		{"uri": "/tmp/a/y.feature", "id": "y", "name": "Y", "line": 1, "elements": [{"id": "y;one", "line": 3}]}]`
+	second := `[{"uri": "/tmp/b/x.feature", "id": "x", "name": "X", "line": 1, "elements": [{"id": "x;two", "line": 4}]}]`
+
+	data, err := MergeCucumber([][]byte{[]byte(second), []byte(first)})
+	if err != nil {
+		t.Fatalf("Error merging cucumber reports when expecting no err: %v", err)
+	}
+	var merged []struct {
+		ID       string `json:"id"`
+		Elements []struct {
+			ID string `json:"id"`
+		} `json:"elements"`
+	}
+	if err := json.Unmarshal(data, &merged); err != nil {
+		t.Fatalf("Merged cucumber report is not valid json: %v", err)
+	}
+	if len(merged) != 2 || merged[0].ID != "x" || merged[1].ID != "y" {
+		t.Fatalf("Features not merged by id and ordered by name: %s", data)
+	}
+	if len(merged[0].Elements) != 2 || merged[0].Elements[0].ID != "x;two" || merged[0].Elements[1].ID != "x;one" {
+		t.Errorf("Scenarios not merged in line order: %s", data)
+	}
+}
+
+func TestMergeShardsPushLatency(t *testing.T) {
+	shard := func(samples ...time.Duration) types.Results {
+		variant := types.VariantResults{Name: "sotw aggregated", PushLatency: types.NewPushLatency(samples)}
+		return types.Results{ResultsByVariant: []types.VariantResults{variant}}
+	}
+	merged := MergeShards([]types.Results{shard(2*time.Millisecond, 4*time.Millisecond), shard(30 * time.Millisecond)})
+	push := merged.ResultsByVariant[0].PushLatency
+	if push == nil || merged.PushLatency == nil {
+		t.Fatalf("Push latency not merged: %+v", merged)
+	}
+	if push.Samples != 3 || push.Mean != 12*time.Millisecond || push.Max != 30*time.Millisecond {
+		t.Errorf("Merged push latency not as expected: %+v", push.Latency)
+	}
+	// the median falls in the bucket up to 5ms.
+	if push.P50 != 5*time.Millisecond {
+		t.Errorf("Wrong merged median. expected: 5ms, actual: %v", push.P50)
+	}
+	total := 0
+	for _, bucket := range push.Histogram {
+		total += bucket.Count
+	}
+	if total != 3 {
+		t.Errorf("Histogram has %v samples, expected 3", total)
+	}
+}
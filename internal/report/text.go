@@ -0,0 +1,254 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ii/xds-test-harness/internal/types"
+)
+
+// The summary printed at the end of a run.
+func Text(results types.Results) string {
+	var b strings.Builder
+	divider := "-------------------"
+	fmt.Fprintln(&b, "\nTest Suite Finished\n"+divider)
+	fmt.Fprintf(&b, "Ran %v scenarios across %v variants\n\n", results.Total, len(results.Variants))
+	fmt.Fprintln(&b, "Passed: ", results.Passed)
+	if results.Failed > 0 {
+		fmt.Fprintln(&b, "Failed: ", results.Failed)
+	}
+	if results.Skipped > 0 {
+		fmt.Fprintln(&b, "Skipped: ", results.Skipped)
+	}
+	if results.Undefined > 0 {
+		fmt.Fprintln(&b, "Undefined: ", results.Undefined)
+	}
+	if results.Pending > 0 {
+		fmt.Fprintln(&b, "Pending: ", results.Pending)
+	}
+	fmt.Fprintf(&b, "\n(%v steps: %v passed, %v failed)\n", results.Steps.Total, results.Steps.Passed, results.Steps.Failed)
+	if results.PushLatency != nil {
+		fmt.Fprintf(&b, "\nPush latency\n%v", latencyText(results.PushLatency.Latency))
+	}
+	fmt.Fprintf(&b, "\n\nResults broken down by Variant....\n\n")
+	for _, variant := range results.ResultsByVariant {
+		if variant.Total > 0 {
+			fmt.Fprintln(&b, variant.Name+"\n"+divider)
+			fmt.Fprintln(&b, variantResults(variant))
+		}
+	}
+	if results.Coverage != nil {
+		fmt.Fprintln(&b, coverageResults(*results.Coverage))
+	}
+	return b.String()
+}
+
+func coverageResults(coverage types.Coverage) string {
+	var b strings.Builder
+	fmt.Fprintln(&b, "Spec Coverage\n-------------------")
+	for _, level := range coverage.Levels {
+		if level.Scenarios > 0 {
+			fmt.Fprintf(&b, "%v: %v of %v scenarios passed (%.0f%%)\n", strings.ToUpper(level.Level), level.Passed, level.Scenarios, level.PassRate*100)
+		}
+	}
+	fmt.Fprintf(&b, "\nCovered requirements: %v\n", len(coverage.Requirements))
+	for _, r := range coverage.Requirements {
+		level := r.Level
+		if level == "" {
+			level = "not in requirements list"
+		}
+		fmt.Fprintf(&b, "  - %v (%v): %v of %v passed\n", r.ID, level, r.Passed, r.Scenarios)
+	}
+	if len(coverage.Uncovered) > 0 {
+		fmt.Fprintf(&b, "\nRequirements with no scenarios: %v\n", len(coverage.Uncovered))
+		for _, r := range coverage.Uncovered {
+			fmt.Fprintf(&b, "  - %v (%v): %v\n", r.ID, r.Level, r.Description)
+		}
+	}
+	return b.String()
+}
+
+func variantResults(results types.VariantResults) string {
+	total := fmt.Sprintf("Total scenarios: %v\n", results.Total)
+	passed := fmt.Sprintf("Passed: %v\n", results.Passed)
+	failed := fmt.Sprintf("Failed: %v\n", results.Failed)
+	skipped := fmt.Sprintf("Skipped: %v\n", results.Skipped)
+	undefined := fmt.Sprintf("Undefined: %v\n", results.Undefined)
+	pending := fmt.Sprintf("Pending: %v\n", results.Pending)
+	steps := fmt.Sprintf("Steps: %v (%v passed, %v failed)\n", results.Steps.Total, results.Steps.Passed, results.Steps.Failed)
+	var failedTests string
+	if len(results.FailedScenarios) > 0 {
+		failedTests = "Failed Scenarios:\n"
+		for _, test := range results.FailedScenarios {
+			location := fmt.Sprintf("%v:%v", test.Feature, test.Line)
+			if test.Example != "" {
+				location = location + " " + test.Example
+			}
+			failedTests = failedTests +
+				"  - " + test.Name +
+				"\n    At: " + location +
+				fmt.Sprintf("\n    Failed Step: %v (line %v)", test.FailedStep, test.FailedStepLine) +
+				"\n    Error: " + test.Error + "\n"
+		}
+	}
+	var push string
+	if results.PushLatency != nil {
+		l := results.PushLatency
+		push = fmt.Sprintf("Push latency: p50 %v, p90 %v, p99 %v, max %v (%v deliveries)\n", l.P50, l.P90, l.P99, l.Max, l.Samples)
+	}
+	return total + passed + failed + failedTests + skipped + undefined + pending + steps + push + passRates(results.PassRates)
+}
+
+func passRates(rates []types.PassRate) string {
+	if len(rates) == 0 {
+		return ""
+	}
+	flaky, failing := "", ""
+	for _, rate := range rates {
+		line := fmt.Sprintf("  - %v (%v:%v) %v: passed %v of %v runs\n", rate.Name, rate.Feature, rate.Line, rate.Example, rate.Passed, rate.Runs)
+		if rate.Flaky() {
+			flaky += line
+		} else if rate.Passed == 0 {
+			failing += line
+		}
+	}
+	out := ""
+	if flaky != "" {
+		out += "Flaky Scenarios:\n" + flaky
+	}
+	if failing != "" {
+		out += "Never Passed:\n" + failing
+	}
+	return out
+}
+
+// The scenarios a dry run found would run in each variant.
+func PlanText(plans []types.VariantPlan) string {
+	var b strings.Builder
+	divider := "-------------------"
+	for _, plan := range plans {
+		fmt.Fprintf(&b, "%v (%v)\n%v\n", plan.Variant, plan.Tags, divider)
+		for _, scenario := range plan.Scenarios {
+			fmt.Fprintf(&b, "%v:%v %v", scenario.Feature, scenario.Line, scenario.Name)
+			if scenario.Example != "" {
+				fmt.Fprintf(&b, " %v", scenario.Example)
+			}
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%v scenarios would run\n\n", len(plan.Scenarios))
+	}
+	return b.String()
+}
+
+// The summary printed at the end of a load run.
+func LoadText(results types.LoadResults) string {
+	var b strings.Builder
+	divider := "-------------------"
+	fmt.Fprintln(&b, "\nLoad Run Finished\n"+divider)
+	fmt.Fprintf(&b, "%v clients of %v on %v nodes over %v connections, for %v\n", results.Clients, results.Service, results.Nodes, results.Connections, results.Duration)
+	fmt.Fprintf(&b, "Variant: %v, subscribing to %v of %v resources\n\n", results.Variant, results.Subscription, results.Resources)
+	fmt.Fprintf(&b, "Streams opened: %v\n", results.StreamsOpened)
+	fmt.Fprintf(&b, "Stream errors: %v\n", results.StreamErrors)
+	fmt.Fprintf(&b, "Updates: %v (%v adapter errors)\n", results.Updates, results.AdapterErrors)
+	fmt.Fprintf(&b, "Deliveries: %v of %v expected\n", results.Deliveries, results.ExpectedDeliveries)
+	fmt.Fprintf(&b, "\nUpdate latency\n%v\n%v", divider, latencyText(results.Latency))
+	if m := results.ServerMemory; m != nil {
+		fmt.Fprintf(&b, "\nServer memory\n%v\n", divider)
+		fmt.Fprintf(&b, "Start: %v, Peak: %v, End: %v\n", megabytes(m.Start), megabytes(m.Peak), megabytes(m.End))
+	}
+	if len(results.Errors) > 0 {
+		fmt.Fprintln(&b, "\nErrors:")
+		for _, err := range results.Errors {
+			fmt.Fprintf(&b, "  - %v\n", err)
+		}
+	}
+	return b.String()
+}
+
+func latencyText(latency types.Latency) string {
+	if latency.Samples == 0 {
+		return "No samples\n"
+	}
+	return fmt.Sprintf("Samples: %v\nMean: %v, p50: %v, p90: %v, p99: %v, Max: %v\n",
+		latency.Samples, latency.Mean, latency.P50, latency.P90, latency.P99, latency.Max)
+}
+
+func megabytes(bytes uint64) string {
+	return fmt.Sprintf("%.1fMB", float64(bytes)/(1024*1024))
+}
+
+func ModelText(results []types.ModelResults) string {
+	var b strings.Builder
+	divider := "-------------------"
+	fmt.Fprintln(&b, "\nModel Run Finished\n"+divider)
+	for _, result := range results {
+		fmt.Fprintf(&b, "%v, %v: %v of %v sequences passed\n", result.Variant, result.Service, result.Passed, result.Runs)
+		for _, failure := range result.Failures {
+			fmt.Fprintf(&b, "  - seed %v failed at: %v\n", failure.Seed, failure.Step)
+			fmt.Fprintf(&b, "    %v\n", failure.Error)
+			if failure.Flaky {
+				fmt.Fprintf(&b, "    didn't fail again, so wasn't shrunk: %v\n", strings.Join(failure.Ops, "; "))
+				continue
+			}
+			fmt.Fprintf(&b, "    shrunk from %v to %v operations: %v\n", len(failure.Ops), len(failure.Shrunk), strings.Join(failure.Shrunk, "; "))
+		}
+	}
+	return b.String()
+}
+
+func SoakText(results types.SoakResults) string {
+	var b strings.Builder
+	divider := "-------------------"
+	fmt.Fprintln(&b, "\nSoak Run Finished\n"+divider)
+	fmt.Fprintf(&b, "%v clients of %v on %v resources, for %v\n", results.Clients, results.Service, results.Resources, results.Duration)
+	fmt.Fprintf(&b, "Variant: %v\n\n", results.Variant)
+	fmt.Fprintf(&b, "Operations: %v (%v adapter errors)\n", results.Operations, results.AdapterErrors)
+	fmt.Fprintf(&b, "Responses: %v\n", results.Responses)
+	fmt.Fprintf(&b, "Checks: %v (%v failed)\n", results.Checks, results.FailedChecks)
+	fmt.Fprintf(&b, "Stream errors: %v\n", results.StreamErrors)
+	fmt.Fprintf(&b, "Violations: %v\n", results.ViolationCount)
+	if len(results.Snapshots) > 0 {
+		fmt.Fprintf(&b, "\nSnapshots\n%v\n", divider)
+		for _, s := range results.Snapshots {
+			fmt.Fprintf(&b, "%v: %v operations, %v responses, %v of %v checks failed, %v violations\n",
+				s.Elapsed, s.Operations, s.Responses, s.FailedChecks, s.Checks, s.ViolationCount)
+		}
+	}
+	if len(results.Violations) > 0 {
+		fmt.Fprintln(&b, "\nViolations:")
+		for _, v := range results.Violations {
+			fmt.Fprintf(&b, "  - %v\n", v)
+		}
+	}
+	if results.Passed {
+		fmt.Fprintln(&b, "\nPASSED")
+	} else {
+		fmt.Fprintln(&b, "\nFAILED")
+	}
+	return b.String()
+}
+
+func FuzzText(results []types.FuzzResults) string {
+	var b strings.Builder
+	divider := "-------------------"
+	fmt.Fprintln(&b, "\nFuzz Run Finished\n"+divider)
+	for _, result := range results {
+		passed := 0
+		for _, c := range result.Cases {
+			if c.Passed {
+				passed++
+			}
+		}
+		fmt.Fprintf(&b, "%v, %v: %v of %v cases passed (seed %v)\n", result.Variant, result.Service, passed, len(result.Cases), result.Seed)
+		for _, c := range result.Cases {
+			if c.Passed {
+				continue
+			}
+			fmt.Fprintf(&b, "  - %v: %v\n", c.Name, c.Outcome)
+			for _, problem := range c.Problems {
+				fmt.Fprintf(&b, "    %v\n", problem)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,166 @@
+package runner
+
+import (
+	"sync"
+	"time"
+)
+
+// The resources a client was sent, by type url, as they arrive. The stream
+// writes to it while the steps read it, so unlike Validate it has a lock of its
+// own, and steps can wait on it.
+type arrivals struct {
+	sync.Mutex
+	// Counted since the client last subscribed, for the steps on large states.
+	started   map[string]time.Time
+	resources map[string]map[string]bool
+	last      map[string]time.Time
+	largest   map[string]int
+	// What the client has on its current stream: the version each resource
+	// came with, those delta removed, and those in the latest sotw response.
+	versions map[string]map[string]string
+	removed  map[string]map[string]bool
+	latest   map[string]map[string]bool
+	// Every response on the current stream, in the order they came, and on the
+	// stream before it.
+	responses map[string][]response
+	previous  map[string][]response
+}
+
+// The version and nonce a response came with.
+type response struct {
+	version string
+	nonce   string
+}
+
+func newArrivals() *arrivals {
+	a := &arrivals{
+		started:   make(map[string]time.Time),
+		resources: make(map[string]map[string]bool),
+		last:      make(map[string]time.Time),
+		largest:   make(map[string]int),
+	}
+	a.newStream()
+	return a
+}
+
+// Forgets what the client had on its last stream.
+func (a *arrivals) newStream() {
+	a.Lock()
+	defer a.Unlock()
+	a.versions = make(map[string]map[string]string)
+	a.removed = make(map[string]map[string]bool)
+	a.latest = make(map[string]map[string]bool)
+	a.previous = a.responses
+	a.responses = make(map[string][]response)
+}
+
+// Starts timing how long the resources of the type take to arrive.
+func (a *arrivals) start(typeURL string) {
+	a.Lock()
+	defer a.Unlock()
+	a.started[typeURL] = time.Now()
+	a.resources[typeURL] = make(map[string]bool)
+	a.largest[typeURL] = 0
+}
+
+func (a *arrivals) add(typeURL string, resources []string, version string, removed []string, size int, at time.Time) {
+	a.Lock()
+	defer a.Unlock()
+	if a.resources[typeURL] == nil {
+		a.resources[typeURL] = make(map[string]bool)
+	}
+	if a.versions[typeURL] == nil {
+		a.versions[typeURL] = make(map[string]string)
+		a.removed[typeURL] = make(map[string]bool)
+	}
+	added := false
+	a.latest[typeURL] = make(map[string]bool)
+	for _, resource := range resources {
+		if !a.resources[typeURL][resource] {
+			a.resources[typeURL][resource] = true
+			added = true
+		}
+		a.versions[typeURL][resource] = version
+		a.latest[typeURL][resource] = true
+		delete(a.removed[typeURL], resource)
+	}
+	for _, resource := range removed {
+		a.removed[typeURL][resource] = true
+	}
+	if added {
+		a.last[typeURL] = at
+	}
+	if size > a.largest[typeURL] {
+		a.largest[typeURL] = size
+	}
+}
+
+func (a *arrivals) responded(typeURL, version, nonce string) {
+	a.Lock()
+	defer a.Unlock()
+	a.responses[typeURL] = append(a.responses[typeURL], response{version, nonce})
+}
+
+// The nth response of the type on the current stream, counting from 1.
+func (a *arrivals) response(typeURL string, n int) (response, bool) {
+	a.Lock()
+	defer a.Unlock()
+	if n < 1 || n > len(a.responses[typeURL]) {
+		return response{}, false
+	}
+	return a.responses[typeURL][n-1], true
+}
+
+// The latest response of the type on the previous stream with a nonce the current
+// stream hasn't been sent. Nonces are only unique to a stream, so a target that
+// restarted may well send the same ones again.
+func (a *arrivals) previousResponse(typeURL string) (response, bool) {
+	a.Lock()
+	defer a.Unlock()
+	sent := map[string]bool{}
+	for _, current := range a.responses[typeURL] {
+		sent[current.nonce] = true
+	}
+	for i := len(a.previous[typeURL]) - 1; i >= 0; i-- {
+		if earlier := a.previous[typeURL][i]; !sent[earlier.nonce] {
+			return earlier, true
+		}
+	}
+	return response{}, false
+}
+
+func (a *arrivals) responseCount(typeURL string) int {
+	a.Lock()
+	defer a.Unlock()
+	return len(a.responses[typeURL])
+}
+
+// How many resources of the type arrived, and how long after the subscription
+// the last new one did.
+func (a *arrivals) count(typeURL string) (int, time.Duration) {
+	a.Lock()
+	defer a.Unlock()
+	return len(a.resources[typeURL]), a.last[typeURL].Sub(a.started[typeURL])
+}
+
+func (a *arrivals) largestResponse(typeURL string) int {
+	a.Lock()
+	defer a.Unlock()
+	return a.largest[typeURL]
+}
+
+// The version the client has of a resource. A delta client no longer has a
+// resource once it's removed. A sotw client has only what was in the latest
+// response, when every response has the whole state, as LDS and CDS's do.
+func (a *arrivals) version(typeURL, resource string, wholeState bool) (string, bool) {
+	a.Lock()
+	defer a.Unlock()
+	if wholeState && !a.latest[typeURL][resource] {
+		return "", false
+	}
+	if a.removed[typeURL][resource] {
+		return "", false
+	}
+	version, ok := a.versions[typeURL][resource]
+	return version, ok
+}
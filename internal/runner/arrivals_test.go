@@ -0,0 +1,58 @@
+package runner
+
+import (
+	"testing"
+	"time"
+)
+
+func TestArrivals(t *testing.T) {
+	a := newArrivals()
+	a.start(clusterType)
+	started := a.started[clusterType]
+	a.add(clusterType, []string{"resource-1", "resource-2"}, "1", nil, 300, started.Add(10*time.Millisecond))
+	// resources sent again don't move the time the last new one arrived.
+	a.add(clusterType, []string{"resource-2", "resource-3"}, "1", nil, 100, started.Add(20*time.Millisecond))
+	a.add(clusterType, []string{"resource-3"}, "1", nil, 50, started.Add(40*time.Millisecond))
+
+	count, took := a.count(clusterType)
+	if count != 3 || took != 20*time.Millisecond {
+		t.Errorf("Expected 3 resources in 20ms, got %v in %v", count, took)
+	}
+	if largest := a.largestResponse(clusterType); largest != 300 {
+		t.Errorf("Wrong largest response. expected: 300, actual: %v", largest)
+	}
+
+	// subscribing again starts over.
+	a.start(clusterType)
+	if count, _ := a.count(clusterType); count != 0 {
+		t.Errorf("Resources still counted after starting over: %v", count)
+	}
+}
+
+func TestArrivalsKeepResponsesOfTheStream(t *testing.T) {
+	a := newArrivals()
+	a.responded(clusterType, "1", "nonce-1")
+	a.responded(clusterType, "2", "nonce-2")
+	if earlier, ok := a.response(clusterType, 1); !ok || earlier.nonce != "nonce-1" || earlier.version != "1" {
+		t.Errorf("Wrong first response: %+v", earlier)
+	}
+	if _, ok := a.response(clusterType, 3); ok {
+		t.Errorf("Found a response that never came")
+	}
+
+	// nonces are only good on the stream that sent them.
+	a.newStream()
+	if count := a.responseCount(clusterType); count != 0 {
+		t.Errorf("Responses still kept on a new stream: %v", count)
+	}
+
+	// a restarted target can send the nonces of the old stream again.
+	a.responded(clusterType, "1", "nonce-1")
+	if earlier, ok := a.previousResponse(clusterType); !ok || earlier.nonce != "nonce-2" || earlier.version != "2" {
+		t.Errorf("Wrong response from the previous stream: %+v", earlier)
+	}
+	a.responded(clusterType, "2", "nonce-2")
+	if earlier, ok := a.previousResponse(clusterType); ok {
+		t.Errorf("Found a response from the previous stream with a nonce sent again: %+v", earlier)
+	}
+}
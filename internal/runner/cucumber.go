@@ -0,0 +1,84 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/cucumber/godog"
+	"github.com/ii/xds-test-harness/internal/types"
+)
+
+// godog ends the id of a scenario from an outline with its examples and the
+// position of its row in them.
+var exampleRowID = regexp.MustCompile(`;[^;]*;(\d+)$`)
+
+// Undoes what SelectScenarios did to the cucumber report of a run of the selected
+// features, so it reads as the report of a run of the original ones: the lines
+// commented out are dropped from the comments, and example rows are numbered as
+// they are in the original examples.
+func RestoreCucumber(report []byte, selected, original string) ([]byte, error) {
+	suite := godog.TestSuite{Options: &godog.Options{Paths: []string{original}}}
+	features, err := suite.RetrieveFeatures()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read features: %v", err)
+	}
+	comments := map[string]map[int]bool{}
+	rows := map[string]map[int]int{}
+	for _, feature := range features {
+		uri := FeatureURI(original, feature.Uri)
+		comments[uri] = map[int]bool{}
+		for _, comment := range feature.GherkinDocument.Comments {
+			comments[uri][int(comment.Location.Line)] = true
+		}
+		rows[uri] = map[int]int{}
+		for _, child := range feature.GherkinDocument.Feature.Children {
+			if child.Scenario == nil {
+				continue
+			}
+			for _, examples := range child.Scenario.Examples {
+				for i, row := range examples.TableBody {
+					rows[uri][int(row.Location.Line)] = i + 2
+				}
+			}
+		}
+	}
+
+	ran := []types.CukeFeatureJSON{}
+	if err := json.Unmarshal(report, &ran); err != nil {
+		return nil, fmt.Errorf("cannot parse cucumber report: %v", err)
+	}
+	for i, feature := range ran {
+		uri := FeatureURI(selected, feature.URI)
+		kept := []types.CukeComment{}
+		for _, comment := range feature.Comments {
+			if comments[uri][comment.Line] {
+				kept = append(kept, comment)
+			}
+		}
+		ran[i].Comments = kept
+		for j, element := range feature.Elements {
+			position, ok := rows[uri][element.Line]
+			match := exampleRowID.FindStringSubmatchIndex(element.ID)
+			if ok && match != nil {
+				ran[i].Elements[j].ID = element.ID[:match[2]] + strconv.Itoa(position)
+			}
+		}
+	}
+	return json.MarshalIndent(ran, "", "    ")
+}
+
+// Restores the cucumber report written to path, in place.
+func restoreCucumberFile(path, selected, original string) error {
+	report, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("cannot read cucumber report: %v", err)
+	}
+	restored, err := RestoreCucumber(report, selected, original)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(restored, '\n'), 0644)
+}
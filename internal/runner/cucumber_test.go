@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ii/xds-test-harness/internal/types"
+)
+
+func TestRestoreCucumber(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "select.feature"), []byte(selectFeature), 0644); err != nil {
+		t.Fatal(err)
+	}
+	selected, err := SelectScenarios(dir, map[string]bool{`features/select.feature:["LDS"] Outline | "LDS" |`: true})
+	if err != nil {
+		t.Fatalf("Error selecting scenarios when expecting no err: %v", err)
+	}
+	defer os.RemoveAll(selected)
+
+	// as godog reports the run of the selected features, with the LDS row
+	// numbered as the only row of its examples and the CDS row as a comment.
+	report := fmt.Sprintf(`[{
+		"uri": %q, "id": "selecting", "keyword": "Feature", "name": "Selecting", "description": "", "line": 1,
+		"comments": [{"value": "# | \"CDS\" |", "line": 9}],
+		"elements": [{"id": "selecting;[\"lds\"]-outline;;2", "keyword": "Scenario Outline", "line": 10}]
+	}]`, filepath.Join(selected, "select.feature"))
+
+	data, err := RestoreCucumber([]byte(report), selected, dir)
+	if err != nil {
+		t.Fatalf("Error restoring cucumber report when expecting no err: %v", err)
+	}
+	var restored []struct {
+		Comments []types.CukeComment `json:"comments"`
+		Elements []struct {
+			ID      string `json:"id"`
+			Keyword string `json:"keyword"`
+		} `json:"elements"`
+	}
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatalf("Restored report is not valid json: %v", err)
+	}
+	if len(restored[0].Comments) != 0 {
+		t.Errorf("Expected the commented out row dropped from the comments: %+v", restored[0].Comments)
+	}
+	element := restored[0].Elements[0]
+	if element.ID != `selecting;["lds"]-outline;;3` || element.Keyword != "Scenario Outline" {
+		t.Errorf("Expected the LDS row numbered as in the original examples: %+v", element)
+	}
+}
@@ -0,0 +1,183 @@
+package runner
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cucumber/godog"
+	messages "github.com/cucumber/messages-go/v16"
+	"github.com/ii/xds-test-harness/internal/types"
+)
+
+// Writes the embedded features to a temporary directory for godog to read, as it
+// can only read features from disk. Files in the override directory are written
+// over them, so it can replace a built-in feature by using its name, or add new
+// ones. The caller removes the directory when done.
+func WriteFeatures(embedded fs.FS, override string) (dir string, err error) {
+	dir, err = os.MkdirTemp("", "xds-features-")
+	if err != nil {
+		return "", fmt.Errorf("cannot create features directory: %v", err)
+	}
+	if err = copyFeatures(embedded, dir); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	if override != "" {
+		if err = copyFeatures(os.DirFS(override), dir); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+func copyFeatures(from fs.FS, to string) error {
+	return fs.WalkDir(from, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("cannot read features: %v", err)
+		}
+		target := filepath.Join(to, filepath.FromSlash(path))
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		data, err := fs.ReadFile(from, path)
+		if err != nil {
+			return fmt.Errorf("cannot read feature %v: %v", path, err)
+		}
+		return os.WriteFile(target, data, 0644)
+	})
+}
+
+// Gives the uri of a feature in the features directory as it would be in the repo,
+// like features/delta.feature, so scenario IDs don't change with the directory.
+func FeatureURI(dir, uri string) string {
+	if dir == "" {
+		return uri
+	}
+	rel, err := filepath.Rel(dir, uri)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return uri
+	}
+	return "features/" + filepath.ToSlash(rel)
+}
+
+// The parts of godog's feature model we use to find a scenario's location.
+type ScenarioFinder interface {
+	FindScenario(astScenarioID string) *messages.Scenario
+	FindExample(exampleAstID string) (*messages.Examples, *messages.TableRow)
+}
+
+// The line of the scenario in its feature file, or of its example row when it
+// comes from a scenario outline, along with the cells of that row.
+func ScenarioLocation(feature ScenarioFinder, scenario *godog.Scenario) (line int, example string) {
+	if len(scenario.AstNodeIds) > 1 {
+		if _, row := feature.FindExample(scenario.AstNodeIds[1]); row != nil {
+			cells := []string{}
+			for _, cell := range row.Cells {
+				cells = append(cells, cell.Value)
+			}
+			example = "| " + strings.Join(cells, " | ") + " |"
+			return int(row.Location.Line), example
+		}
+	}
+	if s := feature.FindScenario(scenario.AstNodeIds[0]); s != nil {
+		return int(s.Location.Line), example
+	}
+	return 0, example
+}
+
+// Writes a copy of the features in dir with only the scenarios, and examples of
+// scenario outlines, whose IDs are given. The rest are commented out rather than
+// removed, so every scenario keeps its line. The caller removes the directory
+// when done.
+func SelectScenarios(dir string, ids map[string]bool) (selected string, err error) {
+	suite := godog.TestSuite{Options: &godog.Options{Paths: []string{dir}}}
+	features, err := suite.RetrieveFeatures()
+	if err != nil {
+		return "", fmt.Errorf("cannot read features: %v", err)
+	}
+	selected, err = os.MkdirTemp("", "xds-features-")
+	if err != nil {
+		return "", fmt.Errorf("cannot create features directory: %v", err)
+	}
+	if err = copyFeatures(os.DirFS(dir), selected); err != nil {
+		os.RemoveAll(selected)
+		return "", err
+	}
+
+	for _, feature := range features {
+		keep := map[string]bool{}
+		for _, pickle := range feature.Pickles {
+			_, example := ScenarioLocation(feature, pickle)
+			if ids[types.ScenarioID(FeatureURI(dir, pickle.Uri), pickle.Name, example)] {
+				for _, id := range pickle.AstNodeIds {
+					keep[id] = true
+				}
+			}
+		}
+
+		lines := strings.Split(string(feature.Content), "\n")
+		comment := func(from, to int) {
+			for i := from; i <= to && i <= len(lines); i++ {
+				if strings.TrimSpace(lines[i-1]) != "" {
+					lines[i-1] = "# " + lines[i-1]
+				}
+			}
+		}
+		children := feature.GherkinDocument.Feature.Children
+		for i, child := range children {
+			scenario := child.Scenario
+			if scenario == nil {
+				continue
+			}
+			end := len(lines)
+			if i+1 < len(children) {
+				end = childStart(children[i+1]) - 1
+			}
+			if !keep[scenario.Id] {
+				comment(childStart(child), end)
+				continue
+			}
+			for _, examples := range scenario.Examples {
+				for _, row := range examples.TableBody {
+					if !keep[row.Id] {
+						comment(int(row.Location.Line), int(row.Location.Line))
+					}
+				}
+			}
+		}
+
+		rel, err := filepath.Rel(dir, feature.Uri)
+		if err != nil {
+			os.RemoveAll(selected)
+			return "", fmt.Errorf("cannot find feature %v in %v: %v", feature.Uri, dir, err)
+		}
+		if err = os.WriteFile(filepath.Join(selected, rel), []byte(strings.Join(lines, "\n")), 0644); err != nil {
+			os.RemoveAll(selected)
+			return "", fmt.Errorf("cannot write feature %v: %v", rel, err)
+		}
+	}
+	return selected, nil
+}
+
+// The first line of a feature's child, counting the tags above a scenario.
+func childStart(child *messages.FeatureChild) int {
+	switch {
+	case child.Scenario != nil:
+		start := int(child.Scenario.Location.Line)
+		for _, tag := range child.Scenario.Tags {
+			if int(tag.Location.Line) < start {
+				start = int(tag.Location.Line)
+			}
+		}
+		return start
+	case child.Background != nil:
+		return int(child.Background.Location.Line)
+	case child.Rule != nil:
+		return int(child.Rule.Location.Line)
+	}
+	return 0
+}
@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/cucumber/godog"
+)
+
+func TestWriteFeatures(t *testing.T) {
+	embedded := fstest.MapFS{
+		"delta.feature":       {Data: []byte("Feature: built in delta")},
+		"subscribing.feature": {Data: []byte("Feature: built in subscribing")},
+	}
+	override := t.TempDir()
+	if err := os.WriteFile(filepath.Join(override, "delta.feature"), []byte("Feature: our delta"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(override, "extra.feature"), []byte("Feature: extra"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := WriteFeatures(embedded, override)
+	if err != nil {
+		t.Fatalf("Error writing features when expecting no err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	expected := map[string]string{
+		"delta.feature":       "Feature: our delta",
+		"subscribing.feature": "Feature: built in subscribing",
+		"extra.feature":       "Feature: extra",
+	}
+	for name, contents := range expected {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(data) != contents {
+			t.Errorf("Feature %v not written as expected. expected: %q, actual: %q, err: %v", name, contents, data, err)
+		}
+	}
+
+	if uri := FeatureURI(dir, filepath.Join(dir, "delta.feature")); uri != "features/delta.feature" {
+		t.Errorf("Feature uri not made relative: %v", uri)
+	}
+	if uri := FeatureURI(dir, "elsewhere/delta.feature"); uri != "elsewhere/delta.feature" {
+		t.Errorf("Feature uri outside the directory should be unchanged: %v", uri)
+	}
+}
+
+var selectFeature = `Feature: Selecting
+
+  @sotw
+  Scenario Outline: [<xDS>] Outline
+    Given a step with <xDS>
+
+    Examples:
+      | xDS   |
+      | "CDS" |
+      | "LDS" |
+
+  @sotw
+  Scenario: Plain
+    Given a step
+`
+
+func TestSelectScenarios(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "select.feature"), []byte(selectFeature), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ids := map[string]bool{`features/select.feature:["LDS"] Outline | "LDS" |`: true}
+	selected, err := SelectScenarios(dir, ids)
+	if err != nil {
+		t.Fatalf("Error selecting scenarios when expecting no err: %v", err)
+	}
+	defer os.RemoveAll(selected)
+
+	suite := godog.TestSuite{Options: &godog.Options{Paths: []string{selected}}}
+	features, err := suite.RetrieveFeatures()
+	if err != nil {
+		t.Fatalf("Selected features don't parse: %v", err)
+	}
+	pickles := features[0].Pickles
+	if len(pickles) != 1 || pickles[0].Name != `["LDS"] Outline` {
+		t.Fatalf("Expected only the LDS example to be selected, got %v pickles", len(pickles))
+	}
+	if line, _ := ScenarioLocation(features[0], pickles[0]); line != 10 {
+		t.Errorf("Selected example should keep its line. expected: 10, actual: %v", line)
+	}
+}
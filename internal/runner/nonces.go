@@ -0,0 +1,118 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/ii/xds-test-harness/internal/parser"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Sends a request for the resources carrying the nonce, and the version, of an
+// earlier response on the stream than the latest. The target should take it as
+// stale and ignore it.
+func (r *Runner) ClientSendsARequestForResourcesOfServiceWithTheNonceOfResponse(resources, service string, n int) error {
+	typeUrl, err := parser.ServiceToTypeURL(service)
+	if err != nil {
+		return err
+	}
+	earlier, ok := r.arrivals.response(typeUrl, n)
+	if !ok {
+		return fmt.Errorf("the Client has had %v responses for %v, so none is response %v", r.arrivals.responseCount(typeUrl), service, n)
+	}
+	return r.sendWithNonce(typeUrl, resources, earlier.version, earlier.nonce)
+}
+
+// Sends a request for the resources carrying the nonce, and the version, of a
+// response on the stream the Client had before the target restarted. It's stale
+// on the new stream, so the target should ignore it.
+func (r *Runner) ClientSendsARequestForResourcesOfServiceWithANonceFromBeforeTheRestart(resources, service string) error {
+	typeUrl, err := parser.ServiceToTypeURL(service)
+	if err != nil {
+		return err
+	}
+	earlier, ok := r.arrivals.previousResponse(typeUrl)
+	if !ok {
+		return fmt.Errorf("the Client has no nonce for %v from before the restart that the target hasn't sent again since", service)
+	}
+	return r.sendWithNonce(typeUrl, resources, earlier.version, earlier.nonce)
+}
+
+// Sends a request for the resources carrying a nonce of the scenario's choosing,
+// with the version of the latest response.
+func (r *Runner) ClientSendsARequestForResourcesOfServiceWithTheNonce(resources, service, nonce string) error {
+	typeUrl, err := parser.ServiceToTypeURL(service)
+	if err != nil {
+		return err
+	}
+	latest, _ := r.arrivals.response(typeUrl, r.arrivals.responseCount(typeUrl))
+	return r.sendWithNonce(typeUrl, resources, latest.version, nonce)
+}
+
+// Sends the request on the stream without making it the Client's subscription,
+// so the requests acking later responses carry what the Client subscribed to
+// before.
+func (r *Runner) sendWithNonce(typeUrl, resources, version, nonce string) error {
+	if r.Service.Channels == nil {
+		return fmt.Errorf("the Client has no stream to send the request on")
+	}
+	var node *core.Node
+	if !r.NodeOnFirstRequestOnly {
+		node = r.node()
+	}
+	var request *anypb.Any
+	if r.Incremental {
+		request, _ = anypb.New(&discovery.DeltaDiscoveryRequest{
+			Node:                   node,
+			TypeUrl:                typeUrl,
+			ResourceNamesSubscribe: strings.Split(resources, ","),
+			ResponseNonce:          nonce,
+		})
+	} else {
+		request, _ = anypb.New(&discovery.DiscoveryRequest{
+			VersionInfo:   version,
+			Node:          node,
+			ResourceNames: strings.Split(resources, ","),
+			TypeUrl:       typeUrl,
+			ResponseNonce: nonce,
+		})
+	}
+	if r.chosenNonce == nil {
+		r.chosenNonce = make(map[string]int)
+	}
+	r.chosenNonce[typeUrl] = r.arrivals.responseCount(typeUrl)
+	log.Debug().
+		Msgf("Sending request with nonce %q: %v", nonce, request)
+	r.Service.Channels.Req <- request
+	return nil
+}
+
+// Checks the target sent nothing more of the type after the request with a
+// nonce of the scenario's choosing.
+func (r *Runner) ClientReceivesNoResponseToItForService(service string) error {
+	typeUrl, err := parser.ServiceToTypeURL(service)
+	if err != nil {
+		return err
+	}
+	before, ok := r.chosenNonce[typeUrl]
+	if !ok {
+		return fmt.Errorf("the Client sent no request with a nonce of its choosing for %v", service)
+	}
+	done := time.After(3 * time.Second)
+	for {
+		select {
+		case err := <-r.Service.Channels.Err:
+			return fmt.Errorf("stream ended while waiting to see the request ignored: %v", err)
+		case <-done:
+			if after := r.arrivals.responseCount(typeUrl); after > before {
+				latest, _ := r.arrivals.response(typeUrl, after)
+				return fmt.Errorf("the target answered the request with %v responses, the latest at version %v, instead of ignoring it", after-before, latest.version)
+			}
+			return nil
+		}
+	}
+}
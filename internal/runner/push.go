@@ -0,0 +1,189 @@
+package runner
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// A resource of a type on a node, as the adapter changes it.
+type pushKey struct {
+	node     string
+	typeURL  string
+	resource string
+}
+
+// The last adapter call changing a resource, from just before it was made.
+type push struct {
+	seq     int
+	at      time.Time
+	removed bool
+}
+
+// A client receiving a push, and how long the push took to reach it.
+type delivery struct {
+	seq     int
+	latency time.Duration
+}
+
+// Times the adapter calls of a scenario, from each call being made to each of the
+// scenario's clients receiving the resources it changed. The Client and its named
+// clients share one.
+type Pushes struct {
+	sync.Mutex
+	seq        int
+	pushes     map[pushKey]push
+	deliveries map[*Runner]map[pushKey]delivery
+	samples    []time.Duration
+}
+
+func NewPushes() *Pushes {
+	return &Pushes{
+		pushes:     make(map[pushKey]push),
+		deliveries: make(map[*Runner]map[pushKey]delivery),
+	}
+}
+
+// Records an adapter call changing the resources, which can be a comma separated
+// list, just before it's made, as the target can deliver the change before the
+// call returns. The returned func takes it back if the call fails.
+func (p *Pushes) pushing(node, typeURL, resources string, removed bool) (failed func()) {
+	if p == nil {
+		return func() {}
+	}
+	at := time.Now()
+	p.Lock()
+	defer p.Unlock()
+	p.seq++
+	seq := p.seq
+	previous := map[pushKey]push{}
+	for _, resource := range strings.Split(resources, ",") {
+		key := pushKey{node, typeURL, strings.TrimSpace(resource)}
+		if before, ok := p.pushes[key]; ok {
+			previous[key] = before
+		}
+		p.pushes[key] = push{seq, at, removed}
+	}
+	return func() {
+		p.Lock()
+		defer p.Unlock()
+		for _, resource := range strings.Split(resources, ",") {
+			key := pushKey{node, typeURL, strings.TrimSpace(resource)}
+			if p.pushes[key].seq != seq {
+				continue
+			}
+			if before, ok := previous[key]; ok {
+				p.pushes[key] = before
+			} else {
+				delete(p.pushes, key)
+			}
+		}
+	}
+}
+
+// Matches a response the client received to the pushes it delivers: those of the
+// resources in it, and of the resources removed from it. A sotw response removes
+// resources by leaving them out, so for sotw, removed should be nil, and any
+// removed resource the response doesn't have counts as delivered.
+func (p *Pushes) received(client *Runner, typeURL string, resources, removed []string, at time.Time, sotw bool) {
+	if p == nil {
+		return
+	}
+	p.Lock()
+	defer p.Unlock()
+	in := map[string]bool{}
+	for _, resource := range resources {
+		in[resource] = true
+	}
+	gone := map[string]bool{}
+	for _, resource := range removed {
+		gone[resource] = true
+	}
+	if p.deliveries[client] == nil {
+		p.deliveries[client] = make(map[pushKey]delivery)
+	}
+	for key, push := range p.pushes {
+		if key.node != client.NodeID || key.typeURL != typeURL {
+			continue
+		}
+		delivered := (!push.removed && in[key.resource]) ||
+			(push.removed && (gone[key.resource] || (sotw && !in[key.resource])))
+		if !delivered || push.seq <= p.deliveries[client][key].seq {
+			continue
+		}
+		latency := at.Sub(push.at)
+		p.deliveries[client][key] = delivery{push.seq, latency}
+		p.samples = append(p.samples, latency)
+	}
+}
+
+// Whether any adapter call changed the resource on the node.
+func (p *Pushes) has(node, typeURL, resource string) bool {
+	if p == nil {
+		return false
+	}
+	p.Lock()
+	defer p.Unlock()
+	_, ok := p.pushes[pushKey{node, typeURL, resource}]
+	return ok
+}
+
+// How long the last push of the resource took to reach the client, if it has.
+func (p *Pushes) latency(client *Runner, typeURL, resource string) (time.Duration, bool) {
+	if p == nil {
+		return 0, false
+	}
+	p.Lock()
+	defer p.Unlock()
+	key := pushKey{client.NodeID, typeURL, resource}
+	push, ok := p.pushes[key]
+	if !ok {
+		return 0, false
+	}
+	delivery, ok := p.deliveries[client][key]
+	if !ok || delivery.seq != push.seq {
+		return 0, false
+	}
+	return delivery.latency, true
+}
+
+// Every delivery so far, in the order they arrived.
+func (p *Pushes) Samples() []time.Duration {
+	if p == nil {
+		return nil
+	}
+	p.Lock()
+	defer p.Unlock()
+	return append([]time.Duration{}, p.samples...)
+}
+
+// What was measured of each scenario that ran, by suite and pickle, until the xds
+// formatter takes it for the scenario's results.
+var scenarioMeasurements = struct {
+	sync.Mutex
+	measured map[string]Measurements
+}{measured: map[string]Measurements{}}
+
+type Measurements struct {
+	PushSamples      []time.Duration
+	SnapshotDelivery time.Duration
+}
+
+func keepMeasurements(suite, pickle string, r *Runner) {
+	scenarioMeasurements.Lock()
+	defer scenarioMeasurements.Unlock()
+	scenarioMeasurements.measured[suite+"/"+pickle] = Measurements{
+		PushSamples:      r.Pushes.Samples(),
+		SnapshotDelivery: r.snapshotDelivery,
+	}
+}
+
+// Takes what was measured of a scenario when it finished. Suites run their
+// features again with --repeat, so it can only be taken once.
+func TakeMeasurements(suite, pickle string) Measurements {
+	scenarioMeasurements.Lock()
+	defer scenarioMeasurements.Unlock()
+	measured := scenarioMeasurements.measured[suite+"/"+pickle]
+	delete(scenarioMeasurements.measured, suite+"/"+pickle)
+	return measured
+}
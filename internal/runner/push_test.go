@@ -0,0 +1,112 @@
+package runner
+
+import (
+	"testing"
+	"time"
+)
+
+const clusterType = "type.googleapis.com/envoy.config.cluster.v3.Cluster"
+
+func TestPushesMatchesResponsesToAdapterCalls(t *testing.T) {
+	pushes := NewPushes()
+	client := FreshRunner()
+	client.NodeID = "test-id"
+	other := FreshRunner()
+	other.NodeID = "other-id"
+
+	pushes.pushing("test-id", clusterType, "A,B", false)
+	pushed := pushes.pushes[pushKey{"test-id", clusterType, "A"}].at
+	pushes.received(client, clusterType, []string{"A"}, nil, pushed.Add(20*time.Millisecond), true)
+	// the same response again, or one on another node, is no delivery.
+	pushes.received(client, clusterType, []string{"A"}, nil, pushed.Add(40*time.Millisecond), true)
+	pushes.received(other, clusterType, []string{"A", "B"}, nil, pushed.Add(40*time.Millisecond), true)
+
+	latency, ok := pushes.latency(client, clusterType, "A")
+	if !ok || latency != 20*time.Millisecond {
+		t.Errorf("Wrong latency for A. expected: 20ms, actual: %v (%v)", latency, ok)
+	}
+	if _, ok := pushes.latency(client, clusterType, "B"); ok {
+		t.Error("B has a latency when the Client never received it")
+	}
+	if samples := pushes.Samples(); len(samples) != 1 {
+		t.Errorf("Expected a single sample, got: %v", samples)
+	}
+
+	// a later update isn't delivered by a response from before it.
+	pushes.pushing("test-id", clusterType, "A", false)
+	if _, ok := pushes.latency(client, clusterType, "A"); ok {
+		t.Error("A has a latency for an update the Client hasn't received")
+	}
+}
+
+func TestPushesTakesBackFailedAdapterCalls(t *testing.T) {
+	pushes := NewPushes()
+	client := FreshRunner()
+	client.NodeID = "test-id"
+
+	pushes.pushing("test-id", clusterType, "A", false)
+	failed := pushes.pushing("test-id", clusterType, "A,B", false)
+	failed()
+	if pushes.has("test-id", clusterType, "B") {
+		t.Error("B pushed by an adapter call that failed")
+	}
+	pushes.received(client, clusterType, []string{"A", "B"}, nil, time.Now(), true)
+	if samples := pushes.Samples(); len(samples) != 1 {
+		t.Errorf("Expected the earlier push of A to be delivered alone, got: %v", samples)
+	}
+}
+
+func TestPushesOfRemovedResources(t *testing.T) {
+	pushes := NewPushes()
+	client := FreshRunner()
+	client.NodeID = "test-id"
+
+	pushes.pushing("test-id", clusterType, "A", true)
+	pushes.pushing("test-id", clusterType, "B", true)
+	at := time.Now()
+	// sotw removes resources by leaving them out of the response.
+	pushes.received(client, clusterType, []string{"B"}, nil, at, true)
+	if _, ok := pushes.latency(client, clusterType, "A"); !ok {
+		t.Error("A not delivered by a sotw response without it")
+	}
+	if _, ok := pushes.latency(client, clusterType, "B"); ok {
+		t.Error("B delivered by a sotw response that still has it")
+	}
+	// delta removes them by name.
+	pushes.received(client, clusterType, nil, []string{"B"}, at, false)
+	if _, ok := pushes.latency(client, clusterType, "B"); !ok {
+		t.Error("B not delivered by a delta response removing it")
+	}
+}
+
+func TestTakeMeasurements(t *testing.T) {
+	r := FreshRunner()
+	r.Pushes = NewPushes()
+	r.Pushes.samples = []time.Duration{time.Millisecond}
+	keepMeasurements("suite", "1", r)
+	if measured := TakeMeasurements("suite", "1"); len(measured.PushSamples) != 1 {
+		t.Errorf("Expected the kept sample, got: %v", measured.PushSamples)
+	}
+	if measured := TakeMeasurements("suite", "1"); measured.PushSamples != nil {
+		t.Errorf("Measurements taken twice: %v", measured)
+	}
+}
+
+func TestPushedWithin(t *testing.T) {
+	r := FreshRunner()
+	r.NodeID = "test-id"
+	r.Pushes = NewPushes()
+	r.Pushes.pushing("test-id", clusterType, "B", false)
+	at := r.Pushes.pushes[pushKey{"test-id", clusterType, "B"}].at
+	r.Pushes.received(r, clusterType, []string{"A", "B"}, nil, at.Add(100*time.Millisecond), true)
+
+	if err := r.pushedWithin([]string{"A", "B"}, "CDS", 500); err != nil {
+		t.Errorf("Error for a push within the limit when expecting no err: %v", err)
+	}
+	if err := r.pushedWithin([]string{"A", "B"}, "CDS", 50); err == nil {
+		t.Error("No error for a push over the limit when expecting one")
+	}
+	if err := r.pushedWithin([]string{"A"}, "CDS", 500); err == nil {
+		t.Error("No error when no resource was pushed to time")
+	}
+}
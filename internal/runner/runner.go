@@ -14,6 +14,7 @@ import (
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
 	any "google.golang.org/protobuf/types/known/anypb"
 )
 
@@ -27,6 +28,8 @@ var (
 type ClientConfig struct {
 	Port string
 	Conn *grpc.ClientConn
+	// The largest message the client accepts, in bytes. gRPC's default of 4MB when 0.
+	MaxRecvMsgSize int
 }
 
 type Cache struct {
@@ -38,6 +41,8 @@ type Cache struct {
 type ValidateResource struct {
 	Version string
 	Nonce   string
+	// Only set by delta responses, which version each resource.
+	ResourceVersion string
 }
 
 type Validate struct {
@@ -67,7 +72,41 @@ type Runner struct {
 	Incremental      bool
 	Service          *XDSService
 	SubscribeRequest *any.Any
-	Validate         *Validate
+	// The resource names currently subscribed to, per type url.
+	// An empty list is a wildcard subscription.
+	Subscriptions map[string][]string
+	Validate      *Validate
+	// Other clients in the scenario, by name. Each is a runner
+	// of its own, with its own stream and validation.
+	Clients map[string]*Runner
+	// Every node the scenario set state for, so it can all be cleared.
+	Nodes map[string]bool
+	// The rest of the node's identity, such as its cluster, locality, and metadata.
+	// Sent alongside NodeID when set.
+	Node *core.Node
+	// When true, only the first request on a stream carries the node.
+	NodeOnFirstRequestOnly bool
+	// Added to the nodes scenarios name, like "edge-node", so suites running side
+	// by side don't share any state on the target.
+	NodeSuffix string
+	// How long a stream stays open before it's cancelled. Defaults to 90 seconds,
+	// plenty for a scenario.
+	StreamTimeout time.Duration
+	// Called with the version and nonce of each response as it arrives, when set,
+	// so a long run can check them over the whole of it.
+	OnNonce func(typeURL, version, nonce string)
+	// Times the scenario's adapter calls until they reach its clients.
+	Pushes *Pushes
+	// When and how each resource first arrived, for the steps on large states.
+	arrivals *arrivals
+	// How long the last large state the Client waited for took to arrive in full.
+	snapshotDelivery time.Duration
+	// How many responses of each type the client had when it last sent a request
+	// with a nonce of the scenario's choosing.
+	chosenNonce map[string]int
+	// Set when the runner dialed the target itself, with its own options, so
+	// closes the connection when done.
+	ownTarget bool
 }
 
 func FreshRunner(current ...*Runner) *Runner {
@@ -75,30 +114,42 @@ func FreshRunner(current ...*Runner) *Runner {
 		adapter     = &ClientConfig{}
 		target      = &ClientConfig{}
 		nodeID      = ""
+		nodeSuffix  = ""
+		node        *core.Node
 		aggregated  = false
 		incremental = false
+		pushes      *Pushes
 	)
 
 	if len(current) > 0 {
 		adapter = current[0].Adapter
 		target = current[0].Target
 		nodeID = current[0].NodeID
+		nodeSuffix = current[0].NodeSuffix
+		node = current[0].Node
 		aggregated = current[0].Aggregated
 		incremental = current[0].Incremental
-
+		pushes = current[0].Pushes
 	}
 
 	validate := NewValidate()
 
 	return &Runner{
-		Adapter:     adapter,
-		Target:      target,
-		NodeID:      nodeID,
-		Cache:       &Cache{},
-		Service:     &XDSService{},
-		Aggregated:  aggregated,
-		Incremental: incremental,
-		Validate:    validate,
+		Adapter:       adapter,
+		Target:        target,
+		NodeID:        nodeID,
+		Node:          node,
+		Cache:         &Cache{},
+		Service:       &XDSService{},
+		Aggregated:    aggregated,
+		Incremental:   incremental,
+		Subscriptions: make(map[string][]string),
+		Validate:      validate,
+		Clients:       make(map[string]*Runner),
+		Nodes:         make(map[string]bool),
+		NodeSuffix:    nodeSuffix,
+		Pushes:        pushes,
+		arrivals:      newArrivals(),
 	}
 }
 
@@ -141,6 +192,18 @@ func (r *Runner) Ack(service *XDSService) {
 	}
 }
 
+// Ends the runner's stream, if it opened one, by stopping its Ack loop. A step
+// may have stopped it already, so this doesn't wait on it for long.
+func (r *Runner) CloseStream() {
+	if r.Service == nil || r.Service.Channels == nil {
+		return
+	}
+	select {
+	case r.Service.Channels.Done <- true:
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
 func (r *Runner) SotwStream(service *XDSService) {
 	sotw := service.Sotw
 	ch := service.Channels
@@ -173,12 +236,19 @@ func (r *Runner) SotwStream(service *XDSService) {
 				return
 			}
 			log.Debug().Msgf("Verison: %v", in.VersionInfo)
+			received := time.Now()
 			for _, resource := range resources {
 				r.Validate.Resources[in.TypeUrl][resource] = ValidateResource{
 					Version: in.VersionInfo,
 					Nonce:   in.Nonce,
 				}
 			}
+			r.Pushes.received(r, in.TypeUrl, resources, nil, received, true)
+			r.arrivals.add(in.TypeUrl, resources, in.VersionInfo, nil, proto.Size(in), received)
+			r.arrivals.responded(in.TypeUrl, in.VersionInfo, in.Nonce)
+			if r.OnNonce != nil {
+				r.OnNonce(in.TypeUrl, in.VersionInfo, in.Nonce)
+			}
 			r.Validate.ResponseCount++
 			res, err := any.New(in)
 			if err != nil {
@@ -232,12 +302,14 @@ func (r *Runner) DeltaStream(service *XDSService) {
 				ch.Err <- fmt.Errorf("[Delta] Error receiving discovery response: %v", err)
 				return
 			}
+			received := time.Now()
 			log.Debug().
 				Msgf("[Delta] Received discovery response: %v", in)
 			for _, resource := range in.GetResources() {
 				r.Validate.Resources[in.TypeUrl][resource.Name] = ValidateResource{
-					Version: in.SystemVersionInfo,
-					Nonce:   in.Nonce,
+					Version:         in.SystemVersionInfo,
+					Nonce:           in.Nonce,
+					ResourceVersion: resource.Version,
 				}
 				delete(r.Validate.RemovedResources[in.TypeUrl], resource.Name)
 			}
@@ -246,6 +318,16 @@ func (r *Runner) DeltaStream(service *XDSService) {
 					Nonce: in.Nonce,
 				}
 			}
+			names := []string{}
+			for _, resource := range in.GetResources() {
+				names = append(names, resource.Name)
+			}
+			r.Pushes.received(r, in.TypeUrl, names, in.GetRemovedResources(), received, false)
+			r.arrivals.add(in.TypeUrl, names, in.SystemVersionInfo, in.GetRemovedResources(), proto.Size(in), received)
+			r.arrivals.responded(in.TypeUrl, in.SystemVersionInfo, in.Nonce)
+			if r.OnNonce != nil {
+				r.OnNonce(in.TypeUrl, in.SystemVersionInfo, in.Nonce)
+			}
 			r.Validate.ResponseCount++
 			res, err := any.New(in)
 			if err != nil {
@@ -280,9 +362,20 @@ func (r *Runner) Stream(service *XDSService) {
 	}
 }
 
+func (r *Runner) streamTimeout() time.Duration {
+	if r.StreamTimeout > 0 {
+		return r.StreamTimeout
+	}
+	return connectTimeout
+}
+
 func connectViaGRPC(client *ClientConfig, server string) (conn *grpc.ClientConn, err error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	conn, err = grpc.DialContext(ctx, client.Port, opts...)
+	dialOpts := opts
+	if client.MaxRecvMsgSize > 0 {
+		dialOpts = append(dialOpts[:len(dialOpts):len(dialOpts)], grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(client.MaxRecvMsgSize)))
+	}
+	conn, err = grpc.DialContext(ctx, client.Port, dialOpts...)
 	cancel()
 	if err != nil {
 		err = fmt.Errorf("cannot connect at %v: %v", client.Port, err)
@@ -335,9 +428,22 @@ func (r *Runner) newAckFromResponse(res *any.Any) (*any.Any, error) {
 }
 
 func (r *Runner) newRequest(resourceNames []string, typeURL string) *any.Any {
+	return r.buildRequest(resourceNames, typeURL, r.node())
+}
+
+// Like newRequest, for requests on a stream that is already open. These leave
+// out the node when the runner should only send it on the first request.
+func (r *Runner) newFollowUpRequest(resourceNames []string, typeURL string) *any.Any {
+	if r.NodeOnFirstRequestOnly {
+		return r.buildRequest(resourceNames, typeURL, nil)
+	}
+	return r.newRequest(resourceNames, typeURL)
+}
+
+func (r *Runner) buildRequest(resourceNames []string, typeURL string, node *core.Node) *any.Any {
 	if r.Incremental {
 		request := &discovery.DeltaDiscoveryRequest{
-			Node:                   &core.Node{Id: r.NodeID},
+			Node:                   node,
 			TypeUrl:                typeURL,
 			ResourceNamesSubscribe: resourceNames,
 		}
@@ -345,10 +451,8 @@ func (r *Runner) newRequest(resourceNames []string, typeURL string) *any.Any {
 		return any
 	} else {
 		request := &discovery.DiscoveryRequest{
-			VersionInfo: "",
-			Node: &core.Node{
-				Id: r.NodeID,
-			},
+			VersionInfo:   "",
+			Node:          node,
 			ResourceNames: resourceNames,
 			TypeUrl:       typeURL,
 		}
@@ -356,3 +460,13 @@ func (r *Runner) newRequest(resourceNames []string, typeURL string) *any.Any {
 		return any
 	}
 }
+
+// The node the runner sends, made of its node ID and the rest of its identity.
+func (r *Runner) node() *core.Node {
+	node := &core.Node{}
+	if r.Node != nil {
+		node = proto.Clone(r.Node).(*core.Node)
+	}
+	node.Id = r.NodeID
+	return node
+}
@@ -27,9 +27,8 @@ func TestFreshRunner(t *testing.T) {
 	}
 }
 
-// Ack should be building a cache of responses
-// and requests, and sending back a new request per response
-// received on the channel.
+// Ack should send the subscribing request, then ack each response received on
+// the channel with its version and nonce, until it's done.
 func TestAck(t *testing.T) {
 
 	r := FreshRunner()
@@ -42,14 +41,20 @@ func TestAck(t *testing.T) {
 	resources := []string{"tui", "kaka", "kakapo"}
 	builder := getBuilder(service)
 	builder.openChannels()
-	builder.setInitResources(resources)
 	r.Service = builder.getService(service)
 
-	request := newRequest(resources, typeURL, r.NodeID)
 	// start the loop with basic request
-	r.SubscribeRequest = request
+	r.SubscribeRequest = r.newRequest(resources, typeURL)
 	go r.Ack(r.Service)
 
+	var subscribe discovery.DiscoveryRequest
+	if err := (<-r.Service.Channels.Req).UnmarshalTo(&subscribe); err != nil {
+		t.Fatalf("Ack did not send a discovery request first: %v", err)
+	}
+	if subscribe.Node.GetId() != "testing" || len(subscribe.ResourceNames) != 3 {
+		t.Errorf("Ack did not start with the subscribing request. Actual: %v", &subscribe)
+	}
+
 	listeners := []*anypb.Any{}
 	for _, name := range resources {
 		dst := &anypb.Any{}
@@ -62,48 +67,34 @@ func TestAck(t *testing.T) {
 		listeners = append(listeners, dst)
 	}
 
-	response := &discovery.DiscoveryResponse{
-		VersionInfo: "1",
-		Resources:   listeners,
-		TypeUrl:     typeURL,
-		// Nonce:        "1",
-	}
-
-	// mock a response received
-	r.Service.Channels.Res <- response
-
-	// flush out the request channel
-	// (in practice, this is done by our Stream fn)
-	req := <-r.Service.Channels.Req
-	log.Debug().Msgf("request %v", req)
-
-	// pass a second response to make sure our cache
-	// and channels can handle multiple responses
-	secondResponse := &discovery.DiscoveryResponse{
-		VersionInfo: "2",
-		Resources:   listeners,
-		TypeUrl:     typeURL,
+	// pass two responses to make sure the channels can handle more than one,
+	// and each gets its own ack.
+	for _, version := range []string{"1", "2"} {
+		response, _ := anypb.New(&discovery.DiscoveryResponse{
+			VersionInfo: version,
+			Resources:   listeners,
+			TypeUrl:     typeURL,
+			Nonce:       "nonce-" + version,
+		})
+		// mock a response received
+		r.Service.Channels.Res <- response
+
+		// flush out the request channel
+		// (in practice, this is done by our Stream fn)
+		var ack discovery.DiscoveryRequest
+		if err := (<-r.Service.Channels.Req).UnmarshalTo(&ack); err != nil {
+			t.Fatalf("Ack did not send a discovery request: %v", err)
+		}
+		log.Debug().Msgf("request %v", &ack)
+		if ack.VersionInfo != version || ack.ResponseNonce != "nonce-"+version || len(ack.ResourceNames) != 3 {
+			t.Errorf("Ack did not ack response %v for the subscribed resources. Actual: %v", version, &ack)
+		}
 	}
 
-	r.Service.Channels.Res <- secondResponse
-	req = <-r.Service.Channels.Req
 	// send a done request which should close Ack
 	// and stop its running
 	r.Service.Channels.Done <- true
-
-	// At this point, we should have three requests and two responses
-	// in our cache (the initial request, the responses above, and an ack
-	// per response)
-	responses := r.Service.Cache.Responses
-	requests := r.Service.Cache.Requests
-	if len(responses) != 2 {
-		t.Errorf("Ack did not cache the expecteda mount of responses. expected 2, actual %v", len(responses))
-	}
-	if len(requests) != 3 {
-		t.Errorf("Ack did not cache the expecteda mount of responses. expected 3, actual %v", len(requests))
-	}
-	// the cache should have the responses in order too
-	if responses[0].VersionInfo != "1" || responses[1].VersionInfo != "2" {
-		t.Error("Responses were not received and/or cached in order. Cannot trust sequence happened correctly")
+	if _, open := <-r.Service.Channels.Req; open {
+		t.Errorf("Ack did not close the request channel when done")
 	}
 }
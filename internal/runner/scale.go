@@ -0,0 +1,100 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ii/xds-test-harness/internal/parser"
+	"github.com/rs/zerolog/log"
+)
+
+// Names count generated resources, like resource-1 through resource-10000.
+func generatedResources(count int) []string {
+	names := make([]string, count)
+	for i := range names {
+		names[i] = fmt.Sprintf("resource-%v", i+1)
+	}
+	return names
+}
+
+// Reads a size like 64KB or 4MB, in bytes.
+func parseSize(size int, unit string) int {
+	if strings.ToUpper(unit) == "MB" {
+		return size * 1024 * 1024
+	}
+	return size * 1024
+}
+
+///////////////////////////////////////////////////////////////////////////////////
+//# Large states
+///////////////////////////////////////////////////////////////////////////////////
+
+// Sets the state to a generated number of resources, far more than a scenario
+// would name one by one.
+func (r *Runner) TargetSetupWithGeneratedResources(count int, service string) error {
+	if count < 1 {
+		return fmt.Errorf("cannot set up the target with %v resources", count)
+	}
+	return r.targetSetup(r.NodeID, service, strings.Join(generatedResources(count), ","), "1")
+}
+
+// Dials the target again, for the Client alone, with a smaller or larger limit
+// on the messages it accepts than the suite's.
+func (r *Runner) TheClientAcceptsMessagesOfAtMost(size int, unit string) error {
+	if r.Service.Sotw != nil || r.Service.Delta != nil {
+		return fmt.Errorf("the Client's message size limit must be set before it subscribes")
+	}
+	target := &ClientConfig{Port: r.Target.Port, MaxRecvMsgSize: parseSize(size, unit)}
+	conn, err := connectViaGRPC(target, "target")
+	if err != nil {
+		return fmt.Errorf("cannot connect to target: %v", err)
+	}
+	target.Conn = conn
+	r.Target = target
+	r.ownTarget = true
+	return nil
+}
+
+// Waits for count resources of the service to arrive, from any number of
+// responses, and records how long they took from the subscription.
+func (r *Runner) ClientReceivesAllResourcesForServiceWithin(count int, service string, seconds int) error {
+	typeUrl, err := parser.ServiceToTypeURL(service)
+	if err != nil {
+		return err
+	}
+	deadline := time.After(time.Duration(seconds) * time.Second)
+	tick := time.NewTicker(50 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case err := <-r.Service.Channels.Err:
+			received, _ := r.arrivals.count(typeUrl)
+			return fmt.Errorf("stream failed after receiving %v of %v resources: %v", received, count, err)
+		case <-deadline:
+			received, _ := r.arrivals.count(typeUrl)
+			return fmt.Errorf("received %v of %v resources within %vs", received, count, seconds)
+		case <-tick.C:
+			received, took := r.arrivals.count(typeUrl)
+			if received < count {
+				continue
+			}
+			r.snapshotDelivery = took
+			log.Debug().
+				Msgf("%v %v resources arrived in %v", received, service, took)
+			return nil
+		}
+	}
+}
+
+func (r *Runner) EveryResponseForServiceWasAtMost(service string, size int, unit string) error {
+	typeUrl, err := parser.ServiceToTypeURL(service)
+	if err != nil {
+		return err
+	}
+	limit := parseSize(size, unit)
+	if largest := r.arrivals.largestResponse(typeUrl); largest > limit {
+		return fmt.Errorf("largest response for %v was %v bytes, more than %v%v", service, largest, size, unit)
+	}
+	return nil
+}
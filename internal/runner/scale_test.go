@@ -0,0 +1,21 @@
+package runner
+
+import (
+	"testing"
+)
+
+func TestGeneratedResources(t *testing.T) {
+	names := generatedResources(10000)
+	if len(names) != 10000 || names[0] != "resource-1" || names[9999] != "resource-10000" {
+		t.Errorf("Resources not generated as expected: %v ... %v", names[0], names[len(names)-1])
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	if size := parseSize(256, "KB"); size != 256*1024 {
+		t.Errorf("Wrong size for 256KB: %v", size)
+	}
+	if size := parseSize(4, "MB"); size != 4*1024*1024 {
+		t.Errorf("Wrong size for 4MB: %v", size)
+	}
+}
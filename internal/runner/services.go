@@ -60,8 +60,8 @@ type XDSService struct {
 
 type serviceBuilder interface {
 	openChannels()
-	setSotwStream(conn *grpc.ClientConn) error
-	setDeltaStream(conn *grpc.ClientConn) error
+	setSotwStream(conn *grpc.ClientConn, timeout time.Duration) error
+	setDeltaStream(conn *grpc.ClientConn, timeout time.Duration) error
 	getService(srv string) *XDSService
 }
 
@@ -81,9 +81,9 @@ func (b *LDSBuilder) openChannels() {
 	}
 }
 
-func (b *LDSBuilder) setSotwStream(conn *grpc.ClientConn) error {
+func (b *LDSBuilder) setSotwStream(conn *grpc.ClientConn, timeout time.Duration) error {
 	client := lds.NewListenerDiscoveryServiceClient(conn)
-	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	stream, err := client.StreamListeners(ctx)
 	if err != nil {
 		defer cancel()
@@ -99,9 +99,9 @@ func (b *LDSBuilder) setSotwStream(conn *grpc.ClientConn) error {
 	return nil
 }
 
-func (b *LDSBuilder) setDeltaStream(conn *grpc.ClientConn) error {
+func (b *LDSBuilder) setDeltaStream(conn *grpc.ClientConn, timeout time.Duration) error {
 	client := lds.NewListenerDiscoveryServiceClient(conn)
-	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	delta, err := client.DeltaListeners(ctx)
 	if err != nil {
 		defer cancel()
@@ -142,9 +142,9 @@ func (b *CDSBuilder) openChannels() {
 	}
 }
 
-func (b *CDSBuilder) setSotwStream(conn *grpc.ClientConn) error {
+func (b *CDSBuilder) setSotwStream(conn *grpc.ClientConn, timeout time.Duration) error {
 	client := cds.NewClusterDiscoveryServiceClient(conn)
-	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	stream, err := client.StreamClusters(ctx)
 	if err != nil {
 		defer cancel()
@@ -160,9 +160,9 @@ func (b *CDSBuilder) setSotwStream(conn *grpc.ClientConn) error {
 	return nil
 }
 
-func (b *CDSBuilder) setDeltaStream(conn *grpc.ClientConn) error {
+func (b *CDSBuilder) setDeltaStream(conn *grpc.ClientConn, timeout time.Duration) error {
 	client := cds.NewClusterDiscoveryServiceClient(conn)
-	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	stream, err := client.DeltaClusters(ctx)
 	if err != nil {
 		defer cancel()
@@ -203,9 +203,9 @@ func (b *RDSBuilder) openChannels() {
 	}
 }
 
-func (b *RDSBuilder) setSotwStream(conn *grpc.ClientConn) error {
+func (b *RDSBuilder) setSotwStream(conn *grpc.ClientConn, timeout time.Duration) error {
 	client := rds.NewRouteDiscoveryServiceClient(conn)
-	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	stream, err := client.StreamRoutes(ctx)
 	if err != nil {
 		defer cancel()
@@ -221,9 +221,9 @@ func (b *RDSBuilder) setSotwStream(conn *grpc.ClientConn) error {
 	return nil
 }
 
-func (b *RDSBuilder) setDeltaStream(conn *grpc.ClientConn) error {
+func (b *RDSBuilder) setDeltaStream(conn *grpc.ClientConn, timeout time.Duration) error {
 	client := rds.NewRouteDiscoveryServiceClient(conn)
-	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	stream, err := client.DeltaRoutes(ctx)
 	if err != nil {
 		defer cancel()
@@ -264,9 +264,9 @@ func (b *EDSBuilder) openChannels() {
 	}
 }
 
-func (b *EDSBuilder) setSotwStream(conn *grpc.ClientConn) error {
+func (b *EDSBuilder) setSotwStream(conn *grpc.ClientConn, timeout time.Duration) error {
 	client := eds.NewEndpointDiscoveryServiceClient(conn)
-	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	stream, err := client.StreamEndpoints(ctx)
 	if err != nil {
 		defer cancel()
@@ -282,9 +282,9 @@ func (b *EDSBuilder) setSotwStream(conn *grpc.ClientConn) error {
 	return nil
 }
 
-func (b *EDSBuilder) setDeltaStream(conn *grpc.ClientConn) error {
+func (b *EDSBuilder) setDeltaStream(conn *grpc.ClientConn, timeout time.Duration) error {
 	client := eds.NewEndpointDiscoveryServiceClient(conn)
-	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	stream, err := client.DeltaEndpoints(ctx)
 	if err != nil {
 		defer cancel()
@@ -325,9 +325,9 @@ func (b *ADSBuilder) openChannels() {
 	}
 }
 
-func (b *ADSBuilder) setSotwStream(conn *grpc.ClientConn) error {
+func (b *ADSBuilder) setSotwStream(conn *grpc.ClientConn, timeout time.Duration) error {
 	client := discovery.NewAggregatedDiscoveryServiceClient(conn)
-	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	stream, err := client.StreamAggregatedResources(ctx)
 	if err != nil {
 		defer cancel()
@@ -343,9 +343,9 @@ func (b *ADSBuilder) setSotwStream(conn *grpc.ClientConn) error {
 	return nil
 }
 
-func (b *ADSBuilder) setDeltaStream(conn *grpc.ClientConn) error {
+func (b *ADSBuilder) setDeltaStream(conn *grpc.ClientConn, timeout time.Duration) error {
 	client := discovery.NewAggregatedDiscoveryServiceClient(conn)
-	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	stream, err := client.DeltaAggregatedResources(ctx)
 	if err != nil {
 		defer cancel()
@@ -0,0 +1,59 @@
+package runner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ii/xds-test-harness/internal/types"
+)
+
+// One of Total parts of a run, so a suite can be split across machines. Index
+// counts from 1.
+type Shard struct {
+	Index int
+	Total int
+}
+
+// Parses a shard given as i/N, like 2/4.
+func ParseShard(s string) (Shard, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 {
+		return Shard{}, fmt.Errorf("shard should be given as i/N, like 2/4, got %q", s)
+	}
+	index, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return Shard{}, fmt.Errorf("cannot parse shard index from %q: %v", s, err)
+	}
+	total, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return Shard{}, fmt.Errorf("cannot parse shard count from %q: %v", s, err)
+	}
+	if total < 1 || index < 1 || index > total {
+		return Shard{}, fmt.Errorf("shard %q should have an index between 1 and its count", s)
+	}
+	return Shard{Index: index, Total: total}, nil
+}
+
+func (s Shard) String() string {
+	return fmt.Sprintf("%v/%v", s.Index, s.Total)
+}
+
+// The IDs of the shard's scenarios, and examples of scenario outlines, from those
+// the suite would run. They are dealt out in the order of the plan, so shards
+// differ in size by at most one and the same features always split the same way.
+// When only is given, just those scenarios are dealt out.
+func (s Shard) Select(plan types.VariantPlan, only map[string]bool) map[string]bool {
+	selected := map[string]bool{}
+	n := 0
+	for _, scenario := range plan.Scenarios {
+		if only != nil && !only[scenario.ID] {
+			continue
+		}
+		if n%s.Total == s.Index-1 {
+			selected[scenario.ID] = true
+		}
+		n++
+	}
+	return selected
+}
@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ii/xds-test-harness/internal/types"
+)
+
+func TestParseShard(t *testing.T) {
+	shard, err := ParseShard("2/4")
+	if err != nil {
+		t.Fatalf("Error parsing shard when expecting no err: %v", err)
+	}
+	if shard.Index != 2 || shard.Total != 4 {
+		t.Errorf("Shard not parsed as expected: %+v", shard)
+	}
+	for _, bad := range []string{"2", "0/4", "5/4", "a/4", "1/0"} {
+		if _, err := ParseShard(bad); err == nil {
+			t.Errorf("Expected an error parsing shard %q", bad)
+		}
+	}
+}
+
+func TestShardSelect(t *testing.T) {
+	plan := types.VariantPlan{}
+	for i := 0; i < 10; i++ {
+		plan.Scenarios = append(plan.Scenarios, types.PlannedScenario{ID: fmt.Sprintf("features/a.feature:s%v", i)})
+	}
+
+	seen := map[string]int{}
+	for i := 1; i <= 3; i++ {
+		selected := Shard{Index: i, Total: 3}.Select(plan, nil)
+		if len(selected) < 3 || len(selected) > 4 {
+			t.Errorf("Shard %v/3 not balanced, has %v scenarios", i, len(selected))
+		}
+		for id := range selected {
+			seen[id]++
+		}
+	}
+	for _, scenario := range plan.Scenarios {
+		if seen[scenario.ID] != 1 {
+			t.Errorf("Expected %v in exactly one shard, was in %v", scenario.ID, seen[scenario.ID])
+		}
+	}
+
+	only := map[string]bool{"features/a.feature:s1": true, "features/a.feature:s7": true}
+	first := Shard{Index: 1, Total: 2}.Select(plan, only)
+	second := Shard{Index: 2, Total: 2}.Select(plan, only)
+	if !first["features/a.feature:s1"] || !second["features/a.feature:s7"] || len(first)+len(second) != 2 {
+		t.Errorf("Expected only the given scenarios dealt out. first: %v, second: %v", first, second)
+	}
+}
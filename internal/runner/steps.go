@@ -3,11 +3,15 @@ package runner
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/cucumber/godog"
 	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
@@ -15,13 +19,28 @@ import (
 	pb "github.com/ii/xds-test-harness/api/adapter"
 	parser "github.com/ii/xds-test-harness/internal/parser"
 	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/anypb"
 )
 
-func (r *Runner) LoadSteps(ctx *godog.ScenarioContext) {
+const (
+	reconnectTimeout = 10 * time.Second
+	// How long the target has to bring a client to the state it should have.
+	settleTimeout = 3 * time.Second
+)
+
+// Where steps are registered. This is a godog ScenarioContext when running, and
+// anything else that wants to know the steps, like the linter, otherwise.
+type StepRegistry interface {
+	Step(expr, stepFunc interface{})
+}
+
+func (r *Runner) LoadSteps(ctx StepRegistry) {
 	// setting state
 	ctx.Step(`^a target setup with service "([^"]*)", resources "([^"]*)", and starting version "([^"]*)"$`, r.TargetSetupWithServiceResourcesAndVersion)
 	ctx.Step(`^a target setup with multiple services "([^"]*)", each with resources "([^"]*)", and starting version "([^"]*)"$`, r.TargetSetupWithServiceResourcesAndVersion)
+	ctx.Step(`^a target setup with the following state:$`, r.TargetSetupWithServicesResourcesAndVersions)
 	// client subscriptions
 	ctx.Step(`^the Client does a wildcard subscription to "([^"]*)"$`, r.ClientDoesAWildcardSubscriptionToService)
 	ctx.Step(`^the Client subscribes to resources "([^"]*)" for "([^"]*)"$`, r.ClientSubscribesToASubsetOfResourcesForService)
@@ -34,18 +53,56 @@ func (r *Runner) LoadSteps(ctx *godog.ScenarioContext) {
 	ctx.Step(`^the Client does not receive any message from "([^"]*)"$`, r.ClientDoesNotReceiveAnyMessageFromService)
 	ctx.Step(`^the Client receives notice that resource "([^"]*)" was removed for service "([^"]*)"$`, r.ClientReceivesNoticeThatResourceWasRemovedForService)
 	ctx.Step(`^the client does not receive resource "([^"]*)" of service "([^"]*)" at version "([^"]*)"$`, r.ClientDoesNotReceiveResourceOfServiceAtVersion)
+	ctx.Step(`^the Client receives the resource "([^"]*)" with resource version "([^"]*)" for "([^"]*)"$`, r.ClientReceivesTheResourceWithResourceVersionForService)
+	// push latency
+	ctx.Step(`^the Client receives the resources "([^"]*)" and version "([^"]*)" for "([^"]*)" within (\d+)ms$`, r.ClientReceivesResourcesAndVersionForServiceWithin)
+	ctx.Step(`^the Client receives notice that resource "([^"]*)" was removed for service "([^"]*)" within (\d+)ms$`, r.ClientReceivesNoticeThatResourceWasRemovedForServiceWithin)
 	// resources are added or updated
 	ctx.Step(`^the resource "([^"]*)" is added to the "([^"]*)" with version "([^"]*)"$`, r.ResourceIsAddedToServiceWithVersion)
 	ctx.Step(`^a resource "([^"]*)" is added to the "([^"]*)" with version "([^"]*)"$`, r.ResourceIsAddedToServiceWithVersion)
 	ctx.Step(`^the resources "([^"]*)" are added to the "([^"]*)" with version "([^"]*)"$`, r.ResourceIsAddedToServiceWithVersion)
 	ctx.Step(`^the resource "([^"]*)" of service "([^"]*)" is updated to version "([^"]*)"$`, r.ResourceOfServiceIsUpdatedToVersion)
 	ctx.Step(`^the resource "([^"]*)" is removed from the "([^"]*)"$`, r.ResourceIsRemovedFromTheService)
+	// large states
+	ctx.Step(`^a target setup with (\d+) "([^"]*)" resources$`, r.TargetSetupWithGeneratedResources)
+	ctx.Step(`^the Client accepts messages of at most (\d+)(KB|MB)$`, r.TheClientAcceptsMessagesOfAtMost)
+	ctx.Step(`^the Client receives all (\d+) resources for "([^"]*)" within (\d+)s$`, r.ClientReceivesAllResourcesForServiceWithin)
+	ctx.Step(`^every response for "([^"]*)" was at most (\d+)(KB|MB)$`, r.EveryResponseForServiceWasAtMost)
+	// eventual state, as the model command writes its reproducers
+	ctx.Step(`^the Client has the resource "([^"]*)" of "([^"]*)" at version "([^"]*)" or later$`, r.ClientHasResourceOfServiceAtVersionOrLater)
+	ctx.Step(`^the Client does not have the resource "([^"]*)" of "([^"]*)"$`, r.ClientDoesNotHaveResourceOfService)
+	ctx.Step(`^the Client reconnects$`, r.TheClientReconnects)
+	// stale nonces
+	ctx.Step(`^the Client sends a request for "([^"]*)" of "([^"]*)" with the nonce of response (\d+)$`, r.ClientSendsARequestForResourcesOfServiceWithTheNonceOfResponse)
+	ctx.Step(`^the Client sends a request for "([^"]*)" of "([^"]*)" with the nonce "([^"]*)"$`, r.ClientSendsARequestForResourcesOfServiceWithTheNonce)
+	ctx.Step(`^the Client sends a request for "([^"]*)" of "([^"]*)" with a nonce from before the restart$`, r.ClientSendsARequestForResourcesOfServiceWithANonceFromBeforeTheRestart)
+	ctx.Step(`^the Client receives no response to it for "([^"]*)"$`, r.ClientReceivesNoResponseToItForService)
 	// misc. client server validation
 	ctx.Step(`^the service never responds more than necessary$`, r.TheServiceNeverRespondsMoreThanNecessary)
 	ctx.Step(`^the resources "([^"]*)" and version "([^"]*)" for "([^"]*)" came in a single response$`, r.ResourcesAndVersionForServiceCameInASingleResponse)
 	ctx.Step(`^for service "([^"]*)", no resource other than "([^"]*)" has same version or nonce$`, r.NoOtherResourceHasSameVersionOrNonce)
 	ctx.Step(`^for service "([^"]*)", no resource other than "([^"]*)" has same nonce$`, r.NoOtherResourceHasSameNonce)
 	ctx.Step(`^the Client sends an ACK to which the "([^"]*)" does not respond$`, r.TheServiceNeverRespondsMoreThanNecessary)
+	// named clients, each with their own stream and node
+	ctx.Step(`^Client "([^"]*)" with node "([^"]*)"$`, r.ClientWithNode)
+	ctx.Step(`^a target setup for node "([^"]*)" with service "([^"]*)", resources "([^"]*)", and starting version "([^"]*)"$`, r.TargetSetupForNodeWithServiceResourcesAndVersion)
+	ctx.Step(`^Client "([^"]*)" subscribes to resources "([^"]*)" for "([^"]*)"$`, r.NamedClientSubscribesToResourcesForService)
+	ctx.Step(`^Client "([^"]*)" receives the resources "([^"]*)" and version "([^"]*)" for "([^"]*)"$`, r.NamedClientReceivesResourcesAndVersionForService)
+	ctx.Step(`^Client "([^"]*)" receives no resources other than "([^"]*)" for "([^"]*)"$`, r.NamedClientReceivesNoResourcesOtherThanForService)
+	ctx.Step(`^Client "([^"]*)" unsubscribes from all resources for "([^"]*)"$`, r.NamedClientUnsubscribesFromAllResourcesForService)
+	ctx.Step(`^Client "([^"]*)" unsubscribes from resource "([^"]*)" for service "([^"]*)"$`, r.NamedClientUnsubscribesFromResourceForService)
+	ctx.Step(`^Client "([^"]*)" does not receive any message from "([^"]*)"$`, r.NamedClientDoesNotReceiveAnyMessageFromService)
+	ctx.Step(`^Client "([^"]*)" does not receive resource "([^"]*)" of service "([^"]*)" at version "([^"]*)"$`, r.NamedClientDoesNotReceiveResourceOfServiceAtVersion)
+	ctx.Step(`^every Client receives the resources "([^"]*)" and version "([^"]*)" for "([^"]*)"$`, r.EveryClientReceivesResourcesAndVersionForService)
+	ctx.Step(`^the resource "([^"]*)" is added to the "([^"]*)" with version "([^"]*)" for node "([^"]*)"$`, r.ResourceIsAddedToServiceWithVersionForNode)
+	ctx.Step(`^the resource "([^"]*)" of service "([^"]*)" is updated to version "([^"]*)" for node "([^"]*)"$`, r.ResourceOfServiceIsUpdatedToVersionForNode)
+	// node identity
+	ctx.Step(`^the Client has the node identity:$`, r.TheClientHasTheNodeIdentity)
+	ctx.Step(`^Client "([^"]*)" with node "([^"]*)" and identity:$`, r.ClientWithNodeAndIdentity)
+	ctx.Step(`^a target setup for Client "([^"]*)" with service "([^"]*)", resources "([^"]*)", and starting version "([^"]*)"$`, r.TargetSetupForClientWithServiceResourcesAndVersion)
+	ctx.Step(`^the Client sends its node only on the first request$`, r.TheClientSendsItsNodeOnlyOnTheFirstRequest)
+	// target restarts
+	ctx.Step(`^the target restarts$`, r.TheTargetRestarts)
 }
 
 ///////////////////////////////////////////////////////////////////////////////////
@@ -55,6 +112,24 @@ func (r *Runner) LoadSteps(ctx *godog.ScenarioContext) {
 // Creates a snapshot to be sent, via the adapter, to the target implementation,
 // setting the state for the rest of the steps.
 func (r *Runner) TargetSetupWithServiceResourcesAndVersion(services, resources, version string) error {
+	return r.targetSetup(r.NodeID, services, resources, version)
+}
+
+// Sets the state for a node other than the runner's own, for
+// scenarios with clients on more than one node.
+func (r *Runner) TargetSetupForNodeWithServiceResourcesAndVersion(node, services, resources, version string) error {
+	return r.targetSetup(r.namedNode(node), services, resources, version)
+}
+
+func (r *Runner) targetSetup(node, services, resources, version string) error {
+	stateRequest, err := newStateRequest(node, services, resources, version)
+	if err != nil {
+		return err
+	}
+	return r.setState(stateRequest)
+}
+
+func newStateRequest(node, services, resources, version string) (*pb.SetStateRequest, error) {
 	resourceNames := strings.Split(resources, ",")
 	serviceNames := strings.Split(services, ",")
 	anyResources := []*anypb.Any{}
@@ -62,49 +137,118 @@ func (r *Runner) TargetSetupWithServiceResourcesAndVersion(services, resources,
 	for _, service := range serviceNames {
 		typeUrl, err := parser.ServiceToTypeURL(service)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		for _, name := range resourceNames {
-			var any *anypb.Any
-			var err error
-			switch typeUrl {
-			case parser.TypeUrlCDS:
-				c := &cluster.Cluster{Name: name}
-				any, err = anypb.New(c)
-			case parser.TypeUrlLDS:
-				l := &listener.Listener{Name: name}
-				any, err = anypb.New(l)
-			case parser.TypeUrlEDS:
-				e := &endpoint.ClusterLoadAssignment{ClusterName: name}
-				any, err = anypb.New(e)
-			case parser.TypeUrlRDS:
-				r := &route.RouteConfiguration{Name: name}
-				any, err = anypb.New(r)
-			}
+			any, err := newAnyResource(typeUrl, name)
 			if err != nil {
-				return err
+				return nil, err
 			}
 			anyResources = append(anyResources, any)
 		}
 
 	}
-	stateRequest := pb.SetStateRequest{
-		Node:      r.NodeID,
+	stateRequest := &pb.SetStateRequest{
+		Node:      node,
 		Version:   version,
 		Resources: anyResources,
 	}
+	return stateRequest, nil
+}
+
+// Like TargetSetupWithServiceResourcesAndVersion, but the state comes from a
+// data table with the columns service, resources, and version, and optionally
+// resource version. A service can span several rows, but all of them have to
+// give it the same version. A row's resource version, when given, versions
+// each of its resources individually, which the incremental variants see in
+// each resource's version.
+func (r *Runner) TargetSetupWithServicesResourcesAndVersions(table *godog.Table) error {
+	rows, err := parser.TableToMaps(table)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("state table has no rows. Expected columns: service, resources, version, and optionally resource version")
+	}
+
+	stateRequest := &pb.SetStateRequest{
+		Node:         r.NodeID,
+		TypeVersions: make(map[string]string),
+	}
+	for _, row := range rows {
+		typeUrl, err := parser.ServiceToTypeURL(row["service"])
+		if err != nil {
+			return err
+		}
+		version := row["version"]
+		if existing, ok := stateRequest.TypeVersions[typeUrl]; ok && existing != version {
+			return fmt.Errorf("service %v is given versions %v and %v. Use the resource version column to version its resources apart", row["service"], existing, version)
+		}
+		stateRequest.TypeVersions[typeUrl] = version
+		if stateRequest.Version == "" {
+			stateRequest.Version = version
+		}
+		for _, name := range strings.Split(row["resources"], ",") {
+			any, err := newAnyResource(typeUrl, name)
+			if err != nil {
+				return err
+			}
+			stateRequest.Resources = append(stateRequest.Resources, any)
+			if row["resource version"] != "" {
+				stateRequest.ResourceVersions = append(stateRequest.ResourceVersions, &pb.ResourceVersion{
+					TypeUrl:      typeUrl,
+					ResourceName: name,
+					Version:      row["resource version"],
+				})
+			}
+		}
+	}
+	return r.setState(stateRequest)
+}
 
+func (r *Runner) setState(stateRequest *pb.SetStateRequest) error {
+	return r.setStateForIdentity(stateRequest, r.identityForNode(stateRequest.Node))
+}
+
+// Sets the state along with the full node it is for, so a target that chooses
+// resources by more than the node ID can key the state the same way.
+func (r *Runner) setStateForIdentity(stateRequest *pb.SetStateRequest, identity *core.Node) error {
+	if identity != nil {
+		any, err := anypb.New(identity)
+		if err != nil {
+			return fmt.Errorf("cannot add node identity to state: %v", err)
+		}
+		stateRequest.NodeIdentity = any
+	}
 	c := pb.NewAdapterClient(r.Adapter.Conn)
 
-	_, err := c.SetState(context.Background(), &stateRequest)
+	_, err := c.SetState(context.Background(), stateRequest)
 	if err != nil {
 		return fmt.Errorf("cannot set target with given state: %v", err)
 	}
+	r.Nodes[stateRequest.Node] = true
 
 	// r.Cache.StartState = snapshot
 	return nil
 }
 
+// Wraps a resource of the given type and name into an anypb, the form
+// the adapter expects its state in.
+func newAnyResource(typeUrl, name string) (*anypb.Any, error) {
+	switch typeUrl {
+	case parser.TypeUrlCDS:
+		return anypb.New(&cluster.Cluster{Name: name})
+	case parser.TypeUrlLDS:
+		return anypb.New(&listener.Listener{Name: name})
+	case parser.TypeUrlEDS:
+		return anypb.New(&endpoint.ClusterLoadAssignment{ClusterName: name})
+	case parser.TypeUrlRDS:
+		return anypb.New(&route.RouteConfiguration{Name: name})
+	default:
+		return nil, fmt.Errorf("cannot create resource for unknown type url: %v", typeUrl)
+	}
+}
+
 ///////////////////////////////////////////////////////////////////////////////////
 //# Client subscriptions
 //////////////////////////////////////////////////////////////////////////////////
@@ -132,40 +276,34 @@ func (r *Runner) ClientSubscribesToServiceForResources(srv string, resources []s
 	}
 
 	r.Validate.Resources[typeUrl] = make(map[string]ValidateResource)
+	r.arrivals.start(typeUrl)
 	// initiate a map for delta tests, in case we get any removed resource notifications
 	r.Validate.RemovedResources[typeUrl] = make(map[string]ValidateResource)
 	for _, resource := range resources {
 		r.Validate.Resources[typeUrl][resource] = ValidateResource{}
 	}
 
+	if r.Incremental {
+		r.Subscriptions[typeUrl] = append(r.Subscriptions[typeUrl], resources...)
+	} else {
+		r.Subscriptions[typeUrl] = resources
+	}
+
 	// check if we are updating existing stream or starting a new one.
 	if (!r.Incremental && r.Service.Sotw != nil) ||
 		(r.Incremental && r.Service.Delta != nil) {
-		request := r.newRequest(resources, typeUrl)
+		request := r.newFollowUpRequest(resources, typeUrl)
 		r.Service.Channels.Req <- request
 		log.Debug().
 			Msgf("Sent new subscribing request: %v\n", request)
 		return nil
 	} else {
-		var builder serviceBuilder
 		if r.Aggregated {
-			builder = getBuilder("ADS")
-		} else {
-			builder = getBuilder(srv)
+			srv = "ADS"
 		}
-		builder.openChannels()
-		if r.Incremental {
-			err := builder.setDeltaStream(r.Target.Conn)
-			if err != nil {
-				return err
-			}
-		} else {
-			err := builder.setSotwStream(r.Target.Conn)
-			if err != nil {
-				return err
-			}
+		if err := r.openService(srv); err != nil {
+			return err
 		}
-		r.Service = builder.getService(srv)
 		request := r.newRequest(resources, typeUrl)
 		r.SubscribeRequest = request
 		log.Debug().
@@ -176,6 +314,30 @@ func (r *Runner) ClientSubscribesToServiceForResources(srv string, resources []s
 	}
 }
 
+// Builds a fresh stream to the target for the given service,
+// setting it as the runner's service.
+func (r *Runner) openService(srv string) error {
+	builder := getBuilder(srv)
+	if builder == nil {
+		return fmt.Errorf("no stream builder for service: %v", srv)
+	}
+	builder.openChannels()
+	r.arrivals.newStream()
+	if r.Incremental {
+		err := builder.setDeltaStream(r.Target.Conn, r.streamTimeout())
+		if err != nil {
+			return err
+		}
+	} else {
+		err := builder.setSotwStream(r.Target.Conn, r.streamTimeout())
+		if err != nil {
+			return err
+		}
+	}
+	r.Service = builder.getService(srv)
+	return nil
+}
+
 func (r *Runner) ClientUpdatesSubscriptionToAResourceForServiceWithVersion(resource, service, version string) error {
 	typeUrl, err := parser.ServiceToTypeURL(service)
 	if err != nil {
@@ -199,6 +361,7 @@ func (r *Runner) ClientUpdatesSubscriptionToAResourceForServiceWithVersion(resou
 		Nonce:   current.Nonce,
 	}
 	r.SubscribeRequest = any
+	r.Subscriptions[typeUrl] = []string{resource}
 
 	log.Debug().Msgf("Sending Request To Update Subscription: %v", request)
 	r.Service.Channels.Req <- any
@@ -226,6 +389,7 @@ func (r *Runner) ClientUnsubscribesFromAllResourcesForService(service string) er
 	r.Validate.Resources[typeURL] = make(map[string]ValidateResource)
 	any, _ := anypb.New(request)
 	r.SubscribeRequest = any
+	r.Subscriptions[typeURL] = []string{""}
 	log.Debug().
 		Msgf("Sending unsubscribe request: %v", request.String())
 	r.Service.Channels.Req <- any
@@ -249,6 +413,17 @@ func (r *Runner) ClientUnsubscribesFromResourceForService(resource, service stri
 
 	delete(r.Validate.Resources[typeUrl], resource)
 	r.SubscribeRequest = any
+	var subscribed []string
+	for _, name := range r.Subscriptions[typeUrl] {
+		if name != resource {
+			subscribed = append(subscribed, name)
+		}
+	}
+	if len(subscribed) > 0 {
+		r.Subscriptions[typeUrl] = subscribed
+	} else {
+		delete(r.Subscriptions, typeUrl)
+	}
 
 	log.Debug().Msgf("Sending Unsubscribe Request: %v", request)
 	r.Service.Channels.Req <- any
@@ -386,11 +561,87 @@ func (r *Runner) ClientDoesNotReceiveResourceOfServiceAtVersion(resource, servic
 	}
 }
 
+// Delta responses version each resource on its own, alongside the system version
+// we check in the other receiving steps. This checks the resource's own version.
+func (r *Runner) ClientReceivesTheResourceWithResourceVersionForService(resource, version, service string) error {
+	typeUrl, err := parser.ServiceToTypeURL(service)
+	if err != nil {
+		return err
+	}
+	done := time.After(3 * time.Second)
+	for {
+		select {
+		case err := <-r.Service.Channels.Err:
+			return fmt.Errorf("could not find expected response within grace period of 3 seconds. %v", err)
+		case <-done:
+			actual, ok := r.Validate.Resources[typeUrl][resource]
+			if !ok {
+				return fmt.Errorf("could not find resource from responses. Expected: %v, Actual: %v", resource, r.Validate.Resources[typeUrl])
+			}
+			if actual.ResourceVersion != version {
+				return fmt.Errorf("found resource, but not correct resource version. Expected: %v, Actual: %v", version, actual.ResourceVersion)
+			}
+			return nil
+		}
+	}
+}
+
+// The receiving steps, checked against a latency objective: the last adapter call
+// changing each of the resources reached the Client within the given milliseconds.
+func (r *Runner) ClientReceivesResourcesAndVersionForServiceWithin(resources, version, service string, ms int) error {
+	if err := r.ClientReceivesResourcesAndVersionForService(resources, version, service); err != nil {
+		return err
+	}
+	return r.pushedWithin(strings.Split(resources, ","), service, ms)
+}
+
+func (r *Runner) ClientReceivesNoticeThatResourceWasRemovedForServiceWithin(resource, service string, ms int) error {
+	if err := r.ClientReceivesNoticeThatResourceWasRemovedForService(resource, service); err != nil {
+		return err
+	}
+	return r.pushedWithin([]string{resource}, service, ms)
+}
+
+func (r *Runner) pushedWithin(resources []string, service string, ms int) error {
+	typeUrl, err := parser.ServiceToTypeURL(service)
+	if err != nil {
+		return err
+	}
+	limit := time.Duration(ms) * time.Millisecond
+	timed := false
+	for _, resource := range resources {
+		// resources only set up with the target weren't pushed by any adapter call.
+		if !r.Pushes.has(r.NodeID, typeUrl, resource) {
+			continue
+		}
+		timed = true
+		latency, ok := r.Pushes.latency(r, typeUrl, resource)
+		if !ok {
+			return fmt.Errorf("cannot time resource %v: the Client has not received the last adapter call changing it", resource)
+		}
+		if latency > limit {
+			return fmt.Errorf("resource %v reached the Client %v after the adapter call, more than %v", resource, latency, limit)
+		}
+	}
+	if !timed {
+		return fmt.Errorf("no adapter call added, updated or removed any of %v to time", resources)
+	}
+	return nil
+}
+
 ///////////////////////////////////////////////////////////////////////////////////
 //# Resources are added or updated
 ///////////////////////////////////////////////////////////////////////////////////
 
 func (r *Runner) ResourceIsAddedToServiceWithVersion(resource, service, version string) error {
+	return r.addResource(r.NodeID, resource, service, version)
+}
+
+func (r *Runner) ResourceIsAddedToServiceWithVersionForNode(resource, service, version, node string) error {
+	return r.addResource(r.namedNode(node), resource, service, version)
+}
+
+func (r *Runner) addResource(node, resource, service, version string) error {
 	typeUrl, err := parser.ServiceToTypeURL(service)
 	if err != nil {
 		return err
@@ -398,14 +649,16 @@ func (r *Runner) ResourceIsAddedToServiceWithVersion(resource, service, version
 
 	c := pb.NewAdapterClient(r.Adapter.Conn)
 	in := &pb.ResourceRequest{
-		Node:         r.NodeID,
+		Node:         node,
 		TypeUrl:      typeUrl,
 		ResourceName: resource,
 		Version:      version,
 	}
 
+	failed := r.Pushes.pushing(node, typeUrl, resource, false)
 	_, err = c.AddResource(context.Background(), in)
 	if err != nil {
+		failed()
 		return fmt.Errorf("cannot add resource using adapter: %v", err)
 	}
 	log.Debug().
@@ -414,6 +667,14 @@ func (r *Runner) ResourceIsAddedToServiceWithVersion(resource, service, version
 }
 
 func (r *Runner) ResourceOfServiceIsUpdatedToVersion(resource, service, version string) error {
+	return r.updateResource(r.NodeID, resource, service, version)
+}
+
+func (r *Runner) ResourceOfServiceIsUpdatedToVersionForNode(resource, service, version, node string) error {
+	return r.updateResource(r.namedNode(node), resource, service, version)
+}
+
+func (r *Runner) updateResource(node, resource, service, version string) error {
 	typeUrl, err := parser.ServiceToTypeURL(service)
 	if err != nil {
 		return err
@@ -421,15 +682,17 @@ func (r *Runner) ResourceOfServiceIsUpdatedToVersion(resource, service, version
 
 	c := pb.NewAdapterClient(r.Adapter.Conn)
 	in := &pb.ResourceRequest{
-		Node:         r.NodeID,
+		Node:         node,
 		TypeUrl:      typeUrl,
 		ResourceName: resource,
 		Version:      version,
 	}
 	log.Debug().
 		Msgf("Updating %v resource %v to version %v", service, resource, version)
+	failed := r.Pushes.pushing(node, typeUrl, resource, false)
 	_, err = c.UpdateResource(context.Background(), in)
 	if err != nil {
+		failed()
 		return fmt.Errorf("cannot update resource using adapter: %v", err)
 	}
 	return nil
@@ -455,8 +718,10 @@ func (r *Runner) ResourceIsRemovedFromTheService(resource, service string) error
 		Version:      currentVersion,
 	}
 
+	failed := r.Pushes.pushing(r.NodeID, typeUrl, resource, true)
 	_, err = c.RemoveResource(context.Background(), request)
 	if err != nil {
+		failed()
 		return fmt.Errorf("cannot remove resource using adapter: %v", err)
 	}
 	log.Debug().
@@ -464,6 +729,93 @@ func (r *Runner) ResourceIsRemovedFromTheService(resource, service string) error
 	return nil
 }
 
+///////////////////////////////////////////////////////////////////////////////////
+//# Eventual state
+///////////////////////////////////////////////////////////////////////////////////
+
+// Waits for the Client to have the resource at the version, or a later one, as
+// it may have changed since.
+func (r *Runner) ClientHasResourceOfServiceAtVersionOrLater(resource, service, version string) error {
+	typeUrl, err := parser.ServiceToTypeURL(service)
+	if err != nil {
+		return err
+	}
+	var actual string
+	var ok bool
+	err = r.settle(func() bool {
+		actual, ok = r.arrivals.version(typeUrl, resource, wholeState(typeUrl, r.Incremental))
+		return ok && versionAtLeast(actual, version)
+	})
+	if err != nil && !ok {
+		return fmt.Errorf("the Client does not have resource %v of %v, expected it at version %v or later", resource, service, version)
+	}
+	if err != nil {
+		return fmt.Errorf("the Client has resource %v of %v at version %v, expected %v or later", resource, service, actual, version)
+	}
+	return nil
+}
+
+// Waits for the Client to no longer have the resource, once it's removed from the
+// target. A sotw client can only tell with LDS and CDS, whose every response has
+// the whole state.
+func (r *Runner) ClientDoesNotHaveResourceOfService(resource, service string) error {
+	typeUrl, err := parser.ServiceToTypeURL(service)
+	if err != nil {
+		return err
+	}
+	if !r.Incremental && !wholeState(typeUrl, false) {
+		return fmt.Errorf("a sotw client is not told when %v resources are removed", service)
+	}
+	err = r.settle(func() bool {
+		_, ok := r.arrivals.version(typeUrl, resource, wholeState(typeUrl, r.Incremental))
+		return !ok
+	})
+	if err != nil {
+		return fmt.Errorf("the Client still has resource %v of %v", resource, service)
+	}
+	return nil
+}
+
+// Closes the Client's stream and opens a new one, subscribing as it was.
+func (r *Runner) TheClientReconnects() error {
+	if r.Service.Channels == nil {
+		return fmt.Errorf("the Client has no stream to reconnect")
+	}
+	previous := r.Service
+	select {
+	case previous.Channels.Done <- true:
+	case <-time.After(1 * time.Second):
+	}
+	return r.reconnect(previous.Name)
+}
+
+// Checks until done, or the target has had settleTimeout to get there.
+func (r *Runner) settle(done func() bool) error {
+	deadline := time.Now().Add(settleTimeout)
+	for !done() {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("not settled within %v", settleTimeout)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return nil
+}
+
+// Whether a sotw response of the type carries every resource the client has.
+func wholeState(typeUrl string, incremental bool) bool {
+	return !incremental && (typeUrl == parser.TypeUrlLDS || typeUrl == parser.TypeUrlCDS)
+}
+
+// Versions are compared as numbers when they are numbers, as scenarios number them.
+func versionAtLeast(actual, expected string) bool {
+	a, errA := strconv.Atoi(actual)
+	e, errE := strconv.Atoi(expected)
+	if errA != nil || errE != nil {
+		return actual == expected
+	}
+	return a >= e
+}
+
 ///////////////////////////////////////////////////////////////////////////////////
 //# Client/server validation
 ///////////////////////////////////////////////////////////////////////////////////
@@ -541,3 +893,280 @@ func (r *Runner) NoOtherResourceHasSameNonce(service, resource string) error {
 	}
 	return nil
 }
+
+///////////////////////////////////////////////////////////////////////////////////
+//# Named clients
+///////////////////////////////////////////////////////////////////////////////////
+
+// Sets up a named client, on its own node, alongside the Client. It shares our
+// connections, but has its own stream and validation, so the steps for it are
+// our usual steps run against its runner.
+func (r *Runner) ClientWithNode(name, node string) error {
+	if _, ok := r.Clients[name]; ok {
+		return fmt.Errorf("client %v was already set up in this scenario", name)
+	}
+	client := FreshRunner(r)
+	client.NodeID = r.namedNode(node)
+	r.Clients[name] = client
+	log.Debug().
+		Msgf("Set up client %v with node %v", name, node)
+	return nil
+}
+
+// The node ID for a node named in a scenario.
+func (r *Runner) namedNode(node string) string {
+	return node + r.NodeSuffix
+}
+
+// Returns the named client, setting it up on our node if
+// the scenario has not already given it one.
+func (r *Runner) client(name string) *Runner {
+	client, ok := r.Clients[name]
+	if !ok {
+		client = FreshRunner(r)
+		r.Clients[name] = client
+	}
+	return client
+}
+
+func (r *Runner) NamedClientSubscribesToResourcesForService(name, resources, service string) error {
+	return r.client(name).ClientSubscribesToASubsetOfResourcesForService(resources, service)
+}
+
+func (r *Runner) NamedClientReceivesResourcesAndVersionForService(name, resources, version, service string) error {
+	return r.client(name).ClientReceivesResourcesAndVersionForService(resources, version, service)
+}
+
+func (r *Runner) NamedClientUnsubscribesFromAllResourcesForService(name, service string) error {
+	return r.client(name).ClientUnsubscribesFromAllResourcesForService(service)
+}
+
+func (r *Runner) NamedClientUnsubscribesFromResourceForService(name, resource, service string) error {
+	return r.client(name).ClientUnsubscribesFromResourceForService(resource, service)
+}
+
+func (r *Runner) NamedClientDoesNotReceiveAnyMessageFromService(name, service string) error {
+	return r.client(name).ClientDoesNotReceiveAnyMessageFromService(service)
+}
+
+func (r *Runner) NamedClientDoesNotReceiveResourceOfServiceAtVersion(name, resource, service, version string) error {
+	return r.client(name).ClientDoesNotReceiveResourceOfServiceAtVersion(resource, service, version)
+}
+
+// Fan out: every client subscribed to the service, the Client included, should
+// receive the update. The clients are checked at the same time, so the step takes
+// as long as checking a single one would.
+func (r *Runner) EveryClientReceivesResourcesAndVersionForService(resources, version, service string) error {
+	typeUrl, err := parser.ServiceToTypeURL(service)
+	if err != nil {
+		return err
+	}
+	subscribed := make(map[string]*Runner)
+	if _, ok := r.Subscriptions[typeUrl]; ok {
+		subscribed["the Client"] = r
+	}
+	for name, client := range r.Clients {
+		if _, ok := client.Subscriptions[typeUrl]; ok {
+			subscribed["Client "+name] = client
+		}
+	}
+	if len(subscribed) == 0 {
+		return fmt.Errorf("no client is subscribed to %v", service)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failures := []string{}
+	for name, client := range subscribed {
+		wg.Add(1)
+		go func(name string, client *Runner) {
+			defer wg.Done()
+			if err := client.ClientReceivesResourcesAndVersionForService(resources, version, service); err != nil {
+				mu.Lock()
+				failures = append(failures, fmt.Sprintf("%v: %v", name, err))
+				mu.Unlock()
+			}
+		}(name, client)
+	}
+	wg.Wait()
+	if len(failures) > 0 {
+		sort.Strings(failures)
+		return fmt.Errorf("%v of %v clients did not receive the update.\n%v", len(failures), len(subscribed), strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+// Checks the named client was never sent a resource outside of the given ones,
+// which for clients on different nodes means it never saw another node's state.
+func (r *Runner) NamedClientReceivesNoResourcesOtherThanForService(name, resources, service string) error {
+	client := r.client(name)
+	typeUrl, err := parser.ServiceToTypeURL(service)
+	if err != nil {
+		return err
+	}
+	expected := make(map[string]bool)
+	for _, resource := range strings.Split(resources, ",") {
+		expected[resource] = true
+	}
+	done := time.After(3 * time.Second)
+	for {
+		select {
+		case err := <-client.Service.Channels.Err:
+			return fmt.Errorf("client %v encountered error while waiting for responses: %v", name, err)
+		case <-done:
+			for resource, info := range client.Validate.Resources[typeUrl] {
+				received := info.Nonce != "" || info.Version != ""
+				if received && !expected[resource] {
+					return fmt.Errorf("client %v on node %v received resource it should not have: %v", name, client.NodeID, resource)
+				}
+			}
+			return nil
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////////
+//# Node identity
+///////////////////////////////////////////////////////////////////////////////////
+
+// Sets the rest of the Client's node, given as yaml in the step's docstring.
+// See parser.ParseNode for the fields it can have.
+func (r *Runner) TheClientHasTheNodeIdentity(identity *godog.DocString) error {
+	node, err := parser.ParseNode(identity.Content)
+	if err != nil {
+		return err
+	}
+	r.Node = node
+	return nil
+}
+
+func (r *Runner) ClientWithNodeAndIdentity(name, node string, identity *godog.DocString) error {
+	if err := r.ClientWithNode(name, node); err != nil {
+		return err
+	}
+	return r.client(name).TheClientHasTheNodeIdentity(identity)
+}
+
+// Sets the state for the named client's full node, so clients sharing a node ID
+// but not a cluster, locality or metadata can each have their own.
+func (r *Runner) TargetSetupForClientWithServiceResourcesAndVersion(name, services, resources, version string) error {
+	client, ok := r.Clients[name]
+	if !ok {
+		return fmt.Errorf("client %v was not set up in this scenario", name)
+	}
+	stateRequest, err := newStateRequest(client.NodeID, services, resources, version)
+	if err != nil {
+		return err
+	}
+	return r.setStateForIdentity(stateRequest, client.node())
+}
+
+func (r *Runner) TheClientSendsItsNodeOnlyOnTheFirstRequest() error {
+	r.NodeOnFirstRequestOnly = true
+	return nil
+}
+
+// The full node a client on the given node ID sends, when it sends more than
+// its ID, so targets that choose resources by node attributes can set state for it.
+func (r *Runner) identityForNode(nodeID string) *core.Node {
+	if r.NodeID == nodeID && r.Node != nil {
+		return r.node()
+	}
+	for _, client := range r.Clients {
+		if client.NodeID == nodeID && client.Node != nil {
+			return client.node()
+		}
+	}
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////////
+//# Target restarts
+///////////////////////////////////////////////////////////////////////////////////
+
+// Restarts the target through the adapter. The target drops our stream when it goes
+// down, so if one was open we reconnect through the same builder and resubscribe
+// to everything we were subscribed to, as a real client would.
+func (r *Runner) TheTargetRestarts() error {
+	c := pb.NewAdapterClient(r.Adapter.Conn)
+	_, err := c.Restart(context.Background(), &pb.RestartRequest{})
+	if status.Code(err) == codes.Unimplemented {
+		log.Info().
+			Msg("Adapter does not implement Restart, leaving test pending")
+		return godog.ErrPending
+	}
+	if err != nil {
+		return fmt.Errorf("cannot restart target using adapter: %v", err)
+	}
+	log.Debug().
+		Msg("Target restarted")
+
+	if r.Service.Channels == nil {
+		return nil
+	}
+	previous := r.Service
+	// shut down the old Ack loop, unless an earlier step already did.
+	select {
+	case previous.Channels.Done <- true:
+	case <-time.After(1 * time.Second):
+	}
+	return r.reconnect(previous.Name)
+}
+
+// Keeps trying to open a new stream until the target is back or we time out,
+// then sends a subscribing request for each type we were subscribed to.
+// Validation starts over, so later steps only see what the restarted target sends.
+func (r *Runner) reconnect(srv string) error {
+	// the Client keeps a single stream, so without ADS it can only be subscribed
+	// to one type on it.
+	if !r.Aggregated && len(r.Subscriptions) > 1 {
+		return fmt.Errorf("the Client is subscribed to %v types, which need a stream each without ADS, but can only reopen one", len(r.Subscriptions))
+	}
+	deadline := time.Now().Add(reconnectTimeout)
+	for {
+		err := r.openService(srv)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("could not reconnect to target within %v of restart: %v", reconnectTimeout, err)
+		}
+		log.Debug().
+			Msgf("Target not ready after restart, retrying: %v", err)
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	typeUrls := []string{}
+	for typeUrl := range r.Subscriptions {
+		typeUrls = append(typeUrls, typeUrl)
+	}
+	sort.Strings(typeUrls)
+
+	requests := []*anypb.Any{}
+	for _, typeUrl := range typeUrls {
+		resources := r.Subscriptions[typeUrl]
+		r.Validate.Resources[typeUrl] = make(map[string]ValidateResource)
+		r.Validate.RemovedResources[typeUrl] = make(map[string]ValidateResource)
+		for _, resource := range resources {
+			r.Validate.Resources[typeUrl][resource] = ValidateResource{}
+		}
+		if len(requests) == 0 {
+			requests = append(requests, r.newRequest(resources, typeUrl))
+		} else {
+			requests = append(requests, r.newFollowUpRequest(resources, typeUrl))
+		}
+	}
+	if len(requests) == 0 {
+		return nil
+	}
+
+	r.SubscribeRequest = requests[0]
+	log.Debug().
+		Msgf("Resubscribing after restart: %v", requests)
+	go r.Stream(r.Service)
+	go r.Ack(r.Service)
+	for _, request := range requests[1:] {
+		r.Service.Channels.Req <- request
+	}
+	return nil
+}
@@ -7,8 +7,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/cucumber/godog"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	pb "github.com/ii/xds-test-harness/api/adapter"
 	"github.com/ii/xds-test-harness/internal/types"
 	"github.com/rs/zerolog/log"
@@ -17,19 +20,74 @@ import (
 type Suite struct {
 	Variant     types.Variant
 	Runner      *Runner
+	Node        *core.Node
 	Aggregated  bool
 	Incremental bool
 	TestWriting bool
 	Buffer      bytes.Buffer
 	Tags        string
+	Features    string
+	// The features Features selects scenarios from, when it was written by
+	// SelectScenarios.
+	SelectedFrom string
+	NodeSuffix   string
+	// The largest message the Client accepts from the target, in bytes. gRPC's
+	// default of 4MB when 0.
+	MaxRecvMsgSize int
+	// How many scenarios run at once. Each gets a node ID of its own when more
+	// than one does.
+	Concurrency int
 	TestSuite   godog.TestSuite
+	// godog's exit status of the last run, the only result there is when
+	// writing tests.
+	Status int
+	// Numbers the scenarios, for their node IDs.
+	scenarios int64
+}
+
+// Scenarios tagged @exclusive, like those restarting the target, disrupt anything
+// else running against it. When suites run side by side, they take the target to
+// themselves, while all other scenarios share it.
+const exclusiveTag = "@exclusive"
+
+var targetLock sync.RWMutex
+
+type runnerKey struct{}
+
+// Adds the scenario's runner to its context.
+func withRunner(ctx context.Context, r *Runner) context.Context {
+	return context.WithValue(ctx, runnerKey{}, r)
+}
+
+// The runner of the scenario the context belongs to.
+func RunnerFrom(ctx context.Context) (*Runner, bool) {
+	r, ok := ctx.Value(runnerKey{}).(*Runner)
+	return r, ok
+}
+
+// Short names for the variants, for the node IDs of suites running side by side.
+func VariantSuffix(variant types.Variant) string {
+	suffixes := map[types.Variant]string{
+		types.SotwNonAggregated:        "sotw-nonagg",
+		types.SotwAggregated:           "sotw-agg",
+		types.IncrementalNonAggregated: "incr-nonagg",
+		types.IncrementalAggregated:    "incr-agg",
+	}
+	return suffixes[variant]
+}
+
+func (s *Suite) Name() string {
+	return fmt.Sprintf("xds Test Suite [%v]", s.Variant)
 }
 
 func (s *Suite) StartRunner(node, adapter, target string) error {
 	s.Runner = FreshRunner()
 	s.Runner.NodeID = node
+	s.Runner.NodeSuffix = s.NodeSuffix
+	s.Runner.Node = s.Node
 	s.Runner.Aggregated = s.Aggregated
 	s.Runner.Incremental = s.Incremental
+	s.Runner.Target.MaxRecvMsgSize = s.MaxRecvMsgSize
 
 	if err := s.Runner.ConnectClient("target", target); err != nil {
 		return fmt.Errorf("cannot connect to target: %v", err)
@@ -70,29 +128,52 @@ func (s *Suite) SetTags(base string) error {
 }
 
 func (s *Suite) ConfigureSuite() {
+	// godog sets up every scenario on its own, so each gets a fresh runner, with its
+	// steps bound to it, and no scenario touches another's state.
 	initScenario := func(ctx *godog.ScenarioContext) {
+		r := s.scenarioRunner()
+		// godog can run the After hooks more than once for a scenario, when it has
+		// more than one undefined step, so only clear up while still locked.
+		var exclusive, locked bool
 		ctx.Before(func(ctx context.Context, sc *godog.Scenario) (context.Context, error) {
+			for _, tag := range sc.Tags {
+				if tag.Name == exclusiveTag {
+					exclusive = true
+				}
+			}
+			if exclusive {
+				targetLock.Lock()
+			} else {
+				targetLock.RLock()
+			}
+			locked = true
 			log.Debug().
-				Msg("Creating Fresh Runner!")
-			s.Runner = FreshRunner(s.Runner)
-			return ctx, nil
+				Msgf("Creating Fresh Runner on node %v", r.NodeID)
+			return withRunner(ctx, r), nil
 		})
 		ctx.After(func(ctx context.Context, sc *godog.Scenario, err error) (context.Context, error) {
 			if err != nil {
 				log.Err(err).Msg("error passed in scenario After hook")
 			}
-			c := pb.NewAdapterClient(s.Runner.Adapter.Conn)
-			clearRequest := &pb.ClearStateRequest{Node: s.Runner.NodeID}
-			clear, err := c.ClearState(context.Background(), clearRequest)
-			if err != nil {
-				log.Err(err).
-					Msg("Couldn't clear state")
+			if !locked {
+				return ctx, nil
 			}
-			log.Debug().
-				Msgf("Clearing State: %v\n", clear.Response)
+			if current, ok := RunnerFrom(ctx); ok {
+				keepMeasurements(s.Name(), sc.Id, current)
+				for _, client := range current.Clients {
+					client.CloseStream()
+				}
+				current.ClearState()
+			}
+			if exclusive {
+				targetLock.Unlock()
+			} else {
+				targetLock.RUnlock()
+			}
+			locked = false
 			return ctx, nil
 		})
-		s.Runner.LoadSteps(ctx)
+		r.LoadSteps(ctx)
 	}
 
 	godogOpts := godog.Options{
@@ -103,17 +184,25 @@ func (s *Suite) ConfigureSuite() {
 		NoColors:            false,
 		Tags:                s.Tags,
 		Format:              "pretty",
-		Concurrency:         0,
+		Concurrency:         s.Concurrency,
+	}
+	if s.Features != "" {
+		godogOpts.Paths = []string{s.Features}
+	}
+	if s.TestWriting {
+		// godog's own status is then all there is, and should fail on undefined
+		// and pending steps, as our results do.
+		godogOpts.Strict = true
 	}
 	if !s.TestWriting { // default is pretty output to stdout.
 		// Only use default when writing tests, otherwise print to our special buffer.
-		outputFile := variantToOutputFile(s.Variant)
+		outputFile := VariantToOutputFile(s.Variant)
 		godogOpts.Format = "xds,cucumber:" + outputFile
 		godogOpts.Output = &s.Buffer
 	}
 
 	suite := godog.TestSuite{
-		Name:                fmt.Sprintf("xds Test Suite [%v]", s.Variant),
+		Name:                s.Name(),
 		ScenarioInitializer: initScenario,
 		Options:             &godogOpts,
 	}
@@ -121,11 +210,90 @@ func (s *Suite) ConfigureSuite() {
 	s.TestSuite = suite
 }
 
+// A runner for a single scenario, from the one the suite started. When scenarios
+// run at once, it is numbered onto a node of its own, along with any nodes the
+// scenario names, so the target keeps their states apart.
+func (s *Suite) scenarioRunner() *Runner {
+	r := FreshRunner(s.Runner)
+	// a scenario can change the Client's identity, so start each from the configured one.
+	r.Node = s.Node
+	r.Pushes = NewPushes()
+	if s.Concurrency > 1 {
+		suffix := fmt.Sprintf("-%v", atomic.AddInt64(&s.scenarios, 1))
+		r.NodeID = r.NodeID + suffix
+		r.NodeSuffix = r.NodeSuffix + suffix
+	}
+	return r
+}
+
+// Clears the state of every node the runner used on the target.
+func (r *Runner) ClearState() {
+	if r.ownTarget {
+		r.Target.Conn.Close()
+	}
+	c := pb.NewAdapterClient(r.Adapter.Conn)
+	nodes := []string{}
+	if r.NodeID != "" {
+		nodes = append(nodes, r.NodeID)
+	}
+	for node := range r.Nodes {
+		if node != r.NodeID {
+			nodes = append(nodes, node)
+		}
+	}
+	for _, node := range nodes {
+		clearRequest := &pb.ClearStateRequest{Node: node}
+		clear, err := c.ClearState(context.Background(), clearRequest)
+		if err != nil {
+			log.Err(err).
+				Msgf("Couldn't clear state for node %v", node)
+			continue
+		}
+		log.Debug().
+			Msgf("Clearing State for node %v: %v\n", node, clear.Response)
+	}
+}
+
+// Lists the scenarios, and examples of scenario outlines, that the suite would run
+// with its tags, without running them. The suite needs configuring first, but
+// not a runner, so nothing connects to the target or adapter.
+func (s *Suite) Plan() (plan types.VariantPlan, err error) {
+	plan = types.VariantPlan{Variant: string(s.Variant), Tags: s.Tags}
+	features, err := s.TestSuite.RetrieveFeatures()
+	if err != nil {
+		return plan, fmt.Errorf("cannot read features: %v", err)
+	}
+	for _, feature := range features {
+		for _, pickle := range feature.Pickles {
+			line, example := ScenarioLocation(feature, pickle)
+			scenario := types.PlannedScenario{
+				Name:    pickle.Name,
+				Feature: FeatureURI(s.Features, pickle.Uri),
+				Line:    line,
+				Example: example,
+			}
+			scenario.ID = types.ScenarioID(scenario.Feature, scenario.Name, scenario.Example)
+			for _, tag := range pickle.Tags {
+				scenario.Tags = append(scenario.Tags, tag.Name)
+			}
+			plan.Scenarios = append(plan.Scenarios, scenario)
+		}
+	}
+	return plan, nil
+}
+
 func (s *Suite) Run() (results types.VariantResults, err error) {
-	s.TestSuite.Run()
+	// the suite can be run more than once, so only read this run's results.
+	s.Buffer.Reset()
+	s.Status = s.TestSuite.Run()
 	if s.TestWriting {
 		return results, err
 	}
+	if s.SelectedFrom != "" {
+		if err = restoreCucumberFile(VariantToOutputFile(s.Variant), s.Features, s.SelectedFrom); err != nil {
+			return results, err
+		}
+	}
 
 	if err = json.Unmarshal(s.Buffer.Bytes(), &results); err != nil {
 		err = fmt.Errorf("error unmarshalling test results: %v", err)
@@ -201,12 +369,25 @@ func UpdateResults(current types.Results, variantResults types.VariantResults) t
 		Skipped:          current.Skipped + int64(variantResults.Skipped),
 		Undefined:        current.Undefined + int64(variantResults.Undefined),
 		Pending:          current.Pending + int64(variantResults.Pending),
+		Steps:            addStepCounts(current.Steps, variantResults.Steps),
+		PushLatency:      types.MergePushLatency(current.PushLatency, variantResults.PushLatency),
 		Variants:         append(current.Variants, variantResults.Name),
 		ResultsByVariant: append(current.ResultsByVariant, variantResults),
 	}
 }
 
-func variantToOutputFile(v types.Variant) string {
+func addStepCounts(a, b types.StepCounts) types.StepCounts {
+	return types.StepCounts{
+		Total:     a.Total + b.Total,
+		Passed:    a.Passed + b.Passed,
+		Failed:    a.Failed + b.Failed,
+		Skipped:   a.Skipped + b.Skipped,
+		Undefined: a.Undefined + b.Undefined,
+		Pending:   a.Pending + b.Pending,
+	}
+}
+
+func VariantToOutputFile(v types.Variant) string {
 	parts := strings.Split(string(v), " ")
 	fileName := strings.Join(parts, "-")
 	return fileName + ".json"
@@ -1,7 +1,9 @@
 package runner
 
 import (
+	"context"
 	"testing"
+
 	"github.com/ii/xds-test-harness/internal/types"
 )
 
@@ -16,3 +18,32 @@ func TestSetTags (t *testing.T) {
 	  t.Errorf("Created tags not matching what is expected. Expected: %v, Actual: %v", expected, suite.Tags)
 	}
 }
+
+func TestScenarioRunner(t *testing.T) {
+	suite := NewSuite(types.SotwAggregated, false)
+	suite.Runner = FreshRunner()
+	suite.Runner.NodeID = "test-id"
+	suite.Runner.NodeSuffix = "-sotw-agg"
+
+	serial := suite.scenarioRunner()
+	if serial.NodeID != "test-id" || serial.namedNode("edge") != "edge-sotw-agg" {
+		t.Errorf("Scenarios run one at a time should keep the suite's nodes. Actual: %v, %v", serial.NodeID, serial.namedNode("edge"))
+	}
+
+	suite.Concurrency = 4
+	first, second := suite.scenarioRunner(), suite.scenarioRunner()
+	if first.NodeID == second.NodeID || first.namedNode("edge") == second.namedNode("edge") {
+		t.Errorf("Scenarios run at once should each have their own nodes. Actual: %v, %v", first.NodeID, second.NodeID)
+	}
+	if first == suite.Runner || first.Validate == second.Validate {
+		t.Errorf("Scenarios should not share a runner or its validation")
+	}
+
+	ctx := withRunner(context.Background(), first)
+	if r, ok := RunnerFrom(ctx); !ok || r != first {
+		t.Errorf("Expected the scenario's runner back from its context")
+	}
+	if _, ok := RunnerFrom(context.Background()); ok {
+		t.Errorf("Expected no runner from a context without one")
+	}
+}
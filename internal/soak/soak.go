@@ -0,0 +1,336 @@
+// Package soak keeps xDS streams open against a target for a long time, while
+// churning their resources through the adapter, and checks throughout that the
+// target keeps to the protocol and its clients keep up. Bugs like leaked watches,
+// growing nonces or stuck streams only show after hours of this.
+package soak
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ii/xds-test-harness/internal/model"
+	"github.com/ii/xds-test-harness/internal/parser"
+	"github.com/ii/xds-test-harness/internal/runner"
+	"github.com/ii/xds-test-harness/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Keeps at most this many distinct violations in the results.
+	maxViolations = 10
+	// A nonce longer than this is taken to be growing with the stream.
+	maxNonceLength = 256
+	// How many of the latest nonces on each stream a new one is checked against,
+	// so a long run doesn't keep every nonce it was sent.
+	nonceWindow = 1000
+)
+
+type Config struct {
+	Target  string
+	Adapter string
+	Variant types.Variant
+	Service string
+	// Clients all subscribe, by name, to every resource there could be, on the
+	// same node.
+	Clients   int
+	Resources int
+	Node      string
+	Duration  time.Duration
+	// How often a resource is added, updated or removed.
+	ChurnInterval time.Duration
+	// How often churn pauses to check every client has what the adapter set.
+	CheckInterval time.Duration
+	// How often to take a snapshot of the counts so far.
+	SnapshotInterval time.Duration
+	Seed             int64
+	// Called with each snapshot as it's taken, when set.
+	OnSnapshot func(types.SoakSnapshot)
+}
+
+func (c Config) validate() error {
+	if c.Clients < 1 || c.Resources < 2 {
+		return fmt.Errorf("there should be at least 1 client and 2 resources")
+	}
+	if c.Duration <= 0 || c.ChurnInterval <= 0 || c.CheckInterval <= 0 || c.SnapshotInterval <= 0 {
+		return fmt.Errorf("duration, churn, check and snapshot intervals should all be more than 0")
+	}
+	return nil
+}
+
+func (c Config) pool() []string {
+	names := []string{}
+	for i := 0; i < c.Resources; i++ {
+		names = append(names, fmt.Sprintf("resource-%v", i))
+	}
+	return names
+}
+
+// The latest nonces a client was sent on its stream, oldest first.
+type recentNonces struct {
+	order []string
+	seen  map[string]bool
+}
+
+// Adds the nonce, forgetting the oldest once there are more than nonceWindow, and
+// returns whether it was already there.
+func (n *recentNonces) add(nonce string) bool {
+	if n.seen[nonce] {
+		return true
+	}
+	n.seen[nonce] = true
+	n.order = append(n.order, nonce)
+	if len(n.order) > nonceWindow {
+		delete(n.seen, n.order[0])
+		n.order = n.order[1:]
+	}
+	return false
+}
+
+// Checks the responses each client receives, and keeps the counts and
+// violations found so far.
+type monitor struct {
+	sync.Mutex
+	incremental  bool
+	nonces       []*recentNonces
+	responses    int
+	streamErrors int
+	violations   types.DistinctErrors
+}
+
+func newMonitor(clients int, incremental bool) *monitor {
+	nonces := make([]*recentNonces, clients)
+	for i := range nonces {
+		nonces[i] = &recentNonces{seen: map[string]bool{}}
+	}
+	return &monitor{
+		incremental: incremental,
+		nonces:      nonces,
+		violations:  types.DistinctErrors{Limit: maxViolations},
+	}
+}
+
+func (m *monitor) response(client int, version, nonce string) {
+	m.Lock()
+	m.responses++
+	seen := m.nonces[client].add(nonce)
+	m.Unlock()
+	switch {
+	case nonce == "":
+		m.violation(fmt.Errorf("client %v was sent a response with no nonce", client))
+	case len(nonce) > maxNonceLength:
+		m.violation(fmt.Errorf("client %v was sent a nonce of %v bytes", client, len(nonce)))
+	case seen:
+		m.violation(fmt.Errorf("client %v was sent nonce %q twice on one stream", client, nonce))
+	}
+	// only sotw responses have to be versioned.
+	if !m.incremental && version == "" {
+		m.violation(fmt.Errorf("client %v was sent a response with no version", client))
+	}
+}
+
+func (m *monitor) streamError(client int, err error) {
+	m.Lock()
+	defer m.Unlock()
+	m.streamErrors++
+	m.violations.Add(fmt.Errorf("client %v stream: %v", client, err))
+}
+
+func (m *monitor) violation(err error) {
+	m.Lock()
+	defer m.Unlock()
+	m.violations.Add(err)
+}
+
+// The next change to make through the adapter: a missing resource added, an
+// existing one removed, or else one updated, always leaving one in place.
+func churn(random *rand.Rand, m *model.Model, pool []string, version string) model.Op {
+	existing, missing := []string{}, []string{}
+	for _, resource := range pool {
+		if _, ok := m.Resources[resource]; ok {
+			existing = append(existing, resource)
+		} else {
+			missing = append(missing, resource)
+		}
+	}
+	switch choice := random.Intn(4); {
+	case choice == 0 && len(missing) > 0:
+		return model.Op{Kind: model.Add, Resources: []string{missing[random.Intn(len(missing))]}, Version: version}
+	case choice == 1 && len(existing) > 1:
+		return model.Op{Kind: model.Remove, Resources: []string{existing[random.Intn(len(existing))]}}
+	}
+	return model.Op{Kind: model.Update, Resources: []string{existing[random.Intn(len(existing))]}, Version: version}
+}
+
+func change(adapter *runner.Runner, op model.Op, service string) error {
+	resource := strings.Join(op.Resources, ",")
+	switch op.Kind {
+	case model.Add:
+		return adapter.ResourceIsAddedToServiceWithVersion(resource, service, op.Version)
+	case model.Remove:
+		return adapter.ResourceIsRemovedFromTheService(resource, service)
+	}
+	return adapter.ResourceOfServiceIsUpdatedToVersion(resource, service, op.Version)
+}
+
+// Waits for the client to have what the model says it should, and returns what
+// it doesn't. A sotw client can only tell a resource is gone with LDS and CDS.
+func check(client *runner.Runner, m *model.Model, service string) []error {
+	errs := []error{}
+	for resource, version := range m.Expected() {
+		if err := client.ClientHasResourceOfServiceAtVersionOrLater(resource, service, version); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if !client.Incremental && service != "LDS" && service != "CDS" {
+		return errs
+	}
+	for _, resource := range m.Gone() {
+		if err := client.ClientDoesNotHaveResourceOfService(resource, service); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func Run(c Config) (results types.SoakResults, err error) {
+	if err = c.validate(); err != nil {
+		return results, err
+	}
+	suite := runner.NewSuite(c.Variant, false)
+	if suite == nil {
+		return results, fmt.Errorf("unknown variant: %v", c.Variant)
+	}
+	if _, err = parser.ServiceToTypeURL(c.Service); err != nil {
+		return results, err
+	}
+	results = types.SoakResults{
+		Variant:   string(c.Variant),
+		Service:   c.Service,
+		Clients:   c.Clients,
+		Resources: c.Resources,
+		Duration:  c.Duration,
+	}
+
+	adapter := runner.FreshRunner()
+	adapter.NodeID = c.Node
+	if err = adapter.ConnectClient("adapter", c.Adapter); err != nil {
+		return results, fmt.Errorf("cannot connect to adapter: %v", err)
+	}
+	defer adapter.ClearState()
+	if err = adapter.ConnectClient("target", c.Target); err != nil {
+		return results, fmt.Errorf("cannot connect to target: %v", err)
+	}
+	adapter.Aggregated = suite.Aggregated
+	adapter.Incremental = suite.Incremental
+	// streams stay open for the whole run, and the checks after it.
+	adapter.StreamTimeout = c.Duration + 5*time.Minute
+
+	pool := c.pool()
+	state := model.New()
+	setup := model.Op{Kind: model.SetState, Resources: pool, Version: "1"}
+	if err = adapter.TargetSetupWithServiceResourcesAndVersion(c.Service, strings.Join(pool, ","), "1"); err != nil {
+		return results, err
+	}
+	_ = state.Apply(setup)
+	_ = state.Apply(model.Op{Kind: model.Subscribe, Resources: pool})
+
+	mon := newMonitor(c.Clients, suite.Incremental)
+	clients := []*runner.Runner{}
+	for i := 0; i < c.Clients; i++ {
+		client := runner.FreshRunner(adapter)
+		client.StreamTimeout = adapter.StreamTimeout
+		index := i
+		client.OnNonce = func(typeURL, version, nonce string) { mon.response(index, version, nonce) }
+		if err = client.ClientSubscribesToServiceForResources(c.Service, pool); err != nil {
+			return results, fmt.Errorf("cannot open stream for client %v: %v", i, err)
+		}
+		clients = append(clients, client)
+		go func(errs chan error) {
+			for err := range errs {
+				mon.streamError(index, err)
+			}
+		}(client.Service.Channels.Err)
+	}
+	log.Info().Msgf("Opened %v streams, soaking for %v", len(clients), c.Duration)
+
+	started := time.Now()
+	snapshot := func() types.SoakSnapshot {
+		mon.Lock()
+		defer mon.Unlock()
+		return types.SoakSnapshot{
+			Elapsed:        time.Since(started).Round(time.Second),
+			Operations:     results.Operations,
+			AdapterErrors:  results.AdapterErrors,
+			Responses:      mon.responses,
+			Checks:         results.Checks,
+			FailedChecks:   results.FailedChecks,
+			StreamErrors:   mon.streamErrors,
+			ViolationCount: mon.violations.Count,
+		}
+	}
+	checkAll := func() {
+		for i, client := range clients {
+			results.Checks++
+			errs := check(client, state, c.Service)
+			if len(errs) > 0 {
+				results.FailedChecks++
+			}
+			for _, err := range errs {
+				mon.violation(fmt.Errorf("client %v: %v", i, err))
+			}
+		}
+	}
+
+	random := rand.New(rand.NewSource(c.Seed))
+	version := 1
+	churnTicker := time.NewTicker(c.ChurnInterval)
+	checkTicker := time.NewTicker(c.CheckInterval)
+	snapshotTicker := time.NewTicker(c.SnapshotInterval)
+	end := time.After(c.Duration)
+soak:
+	for {
+		select {
+		case <-end:
+			break soak
+		case <-churnTicker.C:
+			version++
+			op := churn(random, state, pool, strconv.Itoa(version))
+			if err := change(adapter, op, c.Service); err != nil {
+				results.AdapterErrors++
+				mon.violation(fmt.Errorf("adapter: %v", err))
+				continue
+			}
+			_ = state.Apply(op)
+			results.Operations++
+		case <-checkTicker.C:
+			checkAll()
+		case <-snapshotTicker.C:
+			s := snapshot()
+			results.Snapshots = append(results.Snapshots, s)
+			log.Info().Msgf("%v in: %v operations, %v responses, %v of %v checks failed, %v violations",
+				s.Elapsed, s.Operations, s.Responses, s.FailedChecks, s.Checks, s.ViolationCount)
+			if c.OnSnapshot != nil {
+				c.OnSnapshot(s)
+			}
+		}
+	}
+	churnTicker.Stop()
+	checkTicker.Stop()
+	snapshotTicker.Stop()
+	// the clients should all catch up with the last of the churn.
+	checkAll()
+	results.SoakSnapshot = snapshot()
+	mon.Lock()
+	results.Violations = mon.violations.Messages
+	results.Passed = mon.violations.Count == 0
+	mon.Unlock()
+
+	for _, client := range clients {
+		client.CloseStream()
+	}
+	return results, nil
+}
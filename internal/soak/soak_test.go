@@ -0,0 +1,91 @@
+package soak
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ii/xds-test-harness/internal/model"
+)
+
+func TestMonitor(t *testing.T) {
+	mon := newMonitor(2, false)
+	mon.response(0, "1", "1")
+	mon.response(0, "2", "2")
+	// a nonce is only unique to the stream it's sent on.
+	mon.response(1, "2", "2")
+	if mon.violations.Count != 0 {
+		t.Fatalf("Violations for well-formed responses: %v", mon.violations.Messages)
+	}
+
+	mon.response(0, "3", "2")
+	mon.response(0, "4", "")
+	mon.response(0, "5", strings.Repeat("n", maxNonceLength+1))
+	mon.response(1, "", "3")
+	if mon.violations.Count != 4 || len(mon.violations.Messages) != 4 {
+		t.Errorf("Violations not found as expected: %v", mon.violations.Messages)
+	}
+	if mon.responses != 7 {
+		t.Errorf("Wrong number of responses. expected: 7, actual: %v", mon.responses)
+	}
+
+	// a delta response needn't be versioned.
+	delta := newMonitor(1, true)
+	delta.response(0, "", "1")
+	if delta.violations.Count != 0 {
+		t.Errorf("Violations for an unversioned delta response: %v", delta.violations.Messages)
+	}
+}
+
+func TestMonitorForgetsOldNonces(t *testing.T) {
+	mon := newMonitor(1, false)
+	for i := 0; i <= nonceWindow; i++ {
+		mon.response(0, "1", strconv.Itoa(i))
+	}
+	if len(mon.nonces[0].order) != nonceWindow || len(mon.nonces[0].seen) != nonceWindow {
+		t.Errorf("Expected %v nonces kept, got: %v", nonceWindow, len(mon.nonces[0].order))
+	}
+	// the first nonce has been forgotten, the latest hasn't.
+	mon.response(0, "1", "0")
+	mon.response(0, "1", strconv.Itoa(nonceWindow))
+	if mon.violations.Count != 1 {
+		t.Errorf("Expected only the latest nonce to be seen twice: %v", mon.violations.Messages)
+	}
+}
+
+func TestMonitorKeepsDistinctViolations(t *testing.T) {
+	mon := newMonitor(1, false)
+	for i := 0; i < 3; i++ {
+		mon.streamError(0, errors.New("stream closed"))
+	}
+	for i := 0; i < maxViolations+5; i++ {
+		mon.violation(errors.New(strconv.Itoa(i)))
+	}
+	if mon.streamErrors != 3 || mon.violations.Count != maxViolations+8 || len(mon.violations.Messages) != maxViolations {
+		t.Errorf("Violations not kept as expected: %v stream errors, %v violations, %v", mon.streamErrors, mon.violations.Count, mon.violations.Messages)
+	}
+}
+
+func TestChurn(t *testing.T) {
+	pool := Config{Resources: 3}.pool()
+	m := model.New()
+	_ = m.Apply(model.Op{Kind: model.SetState, Resources: pool, Version: "1"})
+	_ = m.Apply(model.Op{Kind: model.Subscribe, Resources: pool})
+	random := rand.New(rand.NewSource(1))
+	kinds := map[model.Kind]int{}
+	for i := 2; i < 200; i++ {
+		op := churn(random, m, pool, strconv.Itoa(i))
+		if err := m.Apply(op); err != nil {
+			t.Fatalf("Churn made an operation that can't follow: %v: %v", op, err)
+		}
+		if len(m.Resources) == 0 {
+			t.Fatalf("Churn removed every resource")
+		}
+		kinds[op.Kind]++
+	}
+	if kinds[model.Add] == 0 || kinds[model.Update] == 0 || kinds[model.Remove] == 0 {
+		t.Errorf("Churn didn't make every kind of change: %v", kinds)
+	}
+}
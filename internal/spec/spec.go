@@ -0,0 +1,172 @@
+// Package spec traces scenarios to the requirements of the xDS protocol they
+// test, so a run can say which requirements a server meets.
+//
+// Scenarios are tagged with the requirements they cover, like @spec:ack-nack,
+// and with how much a failure matters: @must, @should or @may. The requirements
+// themselves are listed in features/requirements.yaml.
+package spec
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ii/xds-test-harness/internal/types"
+	"github.com/kylelemons/go-gypsy/yaml"
+)
+
+const specTagPrefix = "@spec:"
+
+// Levels from most to least important, as in RFC 2119.
+var Levels = []string{"must", "should", "may"}
+
+type Requirement struct {
+	ID          string
+	Level       string
+	Section     string
+	Description string
+}
+
+func Load(path string) ([]Requirement, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read requirements: %v", err)
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+func Parse(r io.Reader) ([]Requirement, error) {
+	root, err := yaml.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse requirements: %v", err)
+	}
+	entries, ok := root.(yaml.Map)
+	if !ok {
+		return nil, fmt.Errorf("requirements should be a map of ids to requirements")
+	}
+	requirements := []Requirement{}
+	for id, entry := range entries {
+		fields, ok := entry.(yaml.Map)
+		if !ok {
+			return nil, fmt.Errorf("requirement %v should be a map", id)
+		}
+		requirement := Requirement{
+			ID:          id,
+			Level:       scalar(fields["level"]),
+			Section:     scalar(fields["section"]),
+			Description: scalar(fields["description"]),
+		}
+		if levelRank(requirement.Level) < 0 {
+			return nil, fmt.Errorf("requirement %v has level %q, should be one of %v", id, requirement.Level, Levels)
+		}
+		requirements = append(requirements, requirement)
+	}
+	sortRequirements(requirements)
+	return requirements, nil
+}
+
+// The requirements a scenario covers and its level, from its tags.
+func FromTags(tags []string) (ids []string, level string) {
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, specTagPrefix) {
+			ids = append(ids, strings.TrimPrefix(tag, specTagPrefix))
+			continue
+		}
+		if rank := levelRank(strings.TrimPrefix(tag, "@")); rank >= 0 && (level == "" || rank < levelRank(level)) {
+			level = Levels[rank]
+		}
+	}
+	return ids, level
+}
+
+// Counts every scenario that ran, in every variant, against the requirements it
+// is tagged with. Tags for requirements missing from the list are still counted,
+// with no level, so they show up in the report.
+func Coverage(requirements []Requirement, results types.Results) *types.Coverage {
+	byID := map[string]*types.RequirementCoverage{}
+	order := []string{}
+	for _, r := range requirements {
+		byID[r.ID] = &types.RequirementCoverage{ID: r.ID, Level: r.Level, Section: r.Section, Description: r.Description}
+		order = append(order, r.ID)
+	}
+	levels := map[string]*types.LevelCoverage{}
+	for _, level := range Levels {
+		levels[level] = &types.LevelCoverage{Level: level}
+	}
+
+	unknown := []string{}
+	for _, variant := range results.ResultsByVariant {
+		for _, scenario := range variant.Scenarios {
+			ids, level := FromTags(scenario.Tags)
+			if level != "" {
+				levels[level].Scenarios++
+				if scenario.Status == "passed" {
+					levels[level].Passed++
+				}
+			}
+			for _, id := range ids {
+				rc, ok := byID[id]
+				if !ok {
+					rc = &types.RequirementCoverage{ID: id}
+					byID[id] = rc
+					unknown = append(unknown, id)
+				}
+				rc.Scenarios++
+				switch scenario.Status {
+				case "passed":
+					rc.Passed++
+				case "failed":
+					rc.Failed++
+				}
+			}
+		}
+	}
+	sort.Strings(unknown)
+
+	coverage := &types.Coverage{}
+	for _, id := range append(order, unknown...) {
+		if byID[id].Scenarios > 0 {
+			coverage.Requirements = append(coverage.Requirements, *byID[id])
+		} else {
+			coverage.Uncovered = append(coverage.Uncovered, *byID[id])
+		}
+	}
+	for _, level := range Levels {
+		lc := levels[level]
+		if lc.Scenarios > 0 {
+			lc.PassRate = float64(lc.Passed) / float64(lc.Scenarios)
+		}
+		coverage.Levels = append(coverage.Levels, *lc)
+	}
+	return coverage
+}
+
+func levelRank(level string) int {
+	for i, l := range Levels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+func sortRequirements(requirements []Requirement) {
+	sort.Slice(requirements, func(i, j int) bool {
+		a, b := requirements[i], requirements[j]
+		if a.Level != b.Level {
+			return levelRank(a.Level) < levelRank(b.Level)
+		}
+		return a.ID < b.ID
+	})
+}
+
+func scalar(n yaml.Node) string {
+	s, ok := n.(yaml.Scalar)
+	if !ok {
+		return ""
+	}
+	return strings.Trim(strings.TrimSpace(s.String()), `"'`)
+}
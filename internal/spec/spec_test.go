@@ -0,0 +1,94 @@
+package spec
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ii/xds-test-harness/internal/types"
+)
+
+var testRequirements = `
+wildcard:
+  level: must
+  section: How the client specifies what resources to return
+  description: A wildcard request is sent every resource of its type.
+stale-nonce:
+  level: should
+  description: Stale nonces are ignored.
+ack-nack:
+  level: must
+`
+
+func TestParse(t *testing.T) {
+	requirements, err := Parse(strings.NewReader(testRequirements))
+	if err != nil {
+		t.Fatalf("Error parsing requirements when expecting no err: %v", err)
+	}
+	ids := []string{}
+	for _, r := range requirements {
+		ids = append(ids, r.ID)
+	}
+	if strings.Join(ids, ",") != "ack-nack,wildcard,stale-nonce" {
+		t.Errorf("Requirements not sorted by level then id: %v", ids)
+	}
+	if requirements[1].Section != "How the client specifies what resources to return" {
+		t.Errorf("Section not parsed: %+v", requirements[1])
+	}
+
+	_, err = Parse(strings.NewReader("wildcard:\n  level: sometimes\n"))
+	if err == nil {
+		t.Errorf("Expected an error for an unknown level")
+	}
+}
+
+func TestFromTags(t *testing.T) {
+	ids, level := FromTags([]string{"@sotw", "@spec:wildcard", "@should", "@spec:resource-updates", "@must"})
+	if strings.Join(ids, ",") != "wildcard,resource-updates" || level != "must" {
+		t.Errorf("Tags not read as expected. ids: %v, level: %v", ids, level)
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	requirements, err := Parse(strings.NewReader(testRequirements))
+	if err != nil {
+		t.Fatalf("Error parsing requirements when expecting no err: %v", err)
+	}
+	results := types.Results{
+		ResultsByVariant: []types.VariantResults{
+			{Scenarios: []types.ScenarioResult{
+				{Status: "passed", Tags: []string{"@spec:wildcard", "@must"}},
+				{Status: "failed", Tags: []string{"@spec:wildcard", "@must"}},
+				{Status: "passed", Tags: []string{"@spec:made-up", "@should"}},
+			}},
+		},
+	}
+
+	coverage := Coverage(requirements, results)
+	if len(coverage.Requirements) != 2 {
+		t.Fatalf("Expected wildcard and made-up to be covered, got: %+v", coverage.Requirements)
+	}
+	wildcard := coverage.Requirements[0]
+	if wildcard.ID != "wildcard" || wildcard.Scenarios != 2 || wildcard.Passed != 1 || wildcard.Failed != 1 {
+		t.Errorf("Wildcard coverage not as expected: %+v", wildcard)
+	}
+	if coverage.Requirements[1].ID != "made-up" || coverage.Requirements[1].Level != "" {
+		t.Errorf("Requirement not in the list should be covered without a level: %+v", coverage.Requirements[1])
+	}
+	if len(coverage.Uncovered) != 2 || coverage.Uncovered[0].ID != "ack-nack" {
+		t.Errorf("Uncovered requirements not as expected: %+v", coverage.Uncovered)
+	}
+	must := coverage.Levels[0]
+	if must.Level != "must" || must.Scenarios != 2 || must.PassRate != 0.5 {
+		t.Errorf("Must pass rate not as expected: %+v", must)
+	}
+}
+
+func TestRequirementsFile(t *testing.T) {
+	requirements, err := Load("../../features/requirements.yaml")
+	if err != nil {
+		t.Fatalf("Error loading features/requirements.yaml: %v", err)
+	}
+	if len(requirements) == 0 {
+		t.Errorf("Expected requirements in features/requirements.yaml")
+	}
+}
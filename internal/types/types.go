@@ -1,5 +1,14 @@
 package types
 
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
 type Variant string
 
 const (
@@ -73,6 +82,9 @@ type CukeFeatureJSON struct {
 	Elements    []CukeElement `json:"elements,omitempty"`
 }
 
+// Results for a single variant. The counts are of scenarios, with each example
+// of a scenario outline counted as its own scenario. Step counts are kept
+// alongside them, but are not comparable to the list of scenarios.
 type VariantResults struct {
 	Name            string           `json:"name"`
 	Total           int              `json:"total"`
@@ -81,23 +93,445 @@ type VariantResults struct {
 	Skipped         int              `json:"skipped"`
 	Undefined       int              `json:"undefined"`
 	Pending         int              `json:"pending"`
+	Steps           StepCounts       `json:"steps"`
 	FailedScenarios []FailedScenario `json:"failedScenarios"`
+	Scenarios       []ScenarioResult `json:"scenarios"`
+	// With --repeat, how often each scenario passed across the runs.
+	PassRates []PassRate `json:"passRates,omitempty"`
+	// Across the variant's scenarios.
+	PushLatency *PushLatency `json:"pushLatency,omitempty"`
+}
+
+type PassRate struct {
+	ID      string  `json:"id"`
+	Name    string  `json:"name"`
+	Feature string  `json:"feature"`
+	Line    int     `json:"line"`
+	Example string  `json:"example,omitempty"`
+	Runs    int     `json:"runs"`
+	Passed  int     `json:"passed"`
+	Ratio   float64 `json:"ratio"`
+}
+
+// A scenario that passed some runs and failed others.
+func (p PassRate) Flaky() bool {
+	return p.Passed > 0 && p.Passed < p.Runs
+}
+
+type StepCounts struct {
+	Total     int `json:"total"`
+	Passed    int `json:"passed"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+	Undefined int `json:"undefined"`
+	Pending   int `json:"pending"`
+}
+
+// The outcome of a single scenario, or a single example of a scenario outline.
+type ScenarioResult struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Feature string `json:"feature"`
+	// The line of the scenario, or of its example row.
+	Line int `json:"line"`
+	// The cells of the example row, as written in the feature file.
+	Example        string        `json:"example,omitempty"`
+	Status         string        `json:"status"`
+	FailedStep     string        `json:"failedStep,omitempty"`
+	FailedStepLine int           `json:"failedStepLine,omitempty"`
+	Error          string        `json:"error,omitempty"`
+	Duration       time.Duration `json:"duration"`
+	Steps          []StepResult  `json:"steps,omitempty"`
+	Tags           []string      `json:"tags,omitempty"`
+	// How long the scenario's adapter calls took to reach its clients.
+	PushLatency *PushLatency `json:"pushLatency,omitempty"`
+	// How long a large state the scenario waited for took to arrive in full.
+	SnapshotDelivery time.Duration `json:"snapshotDelivery,omitempty"`
+}
+
+// A step as it ran, with the values of any example row filled in.
+type StepResult struct {
+	Keyword string `json:"keyword"`
+	Text    string `json:"text"`
+	Line    int    `json:"line"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
 }
 
 type FailedScenario struct {
-	Name       string `json:"name"`
-	FailedStep string `json:"failedStep"`
-	Line       string `json:"line"`
-	Error      string `json:"error"`
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Feature        string `json:"feature"`
+	Line           int    `json:"line"`
+	Example        string `json:"example,omitempty"`
+	FailedStep     string `json:"failedStep"`
+	FailedStepLine int    `json:"failedStepLine"`
+	Error          string `json:"error"`
+}
+
+// Results files from before failed scenarios had a line of their own give the
+// line of the failed step instead, as "feature:line", so it's read as that.
+func (f *FailedScenario) UnmarshalJSON(data []byte) error {
+	type plain FailedScenario
+	var read struct {
+		plain
+		Line json.RawMessage `json:"line"`
+	}
+	if err := json.Unmarshal(data, &read); err != nil {
+		return err
+	}
+	*f = FailedScenario(read.plain)
+	if len(read.Line) == 0 || string(read.Line) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(read.Line, &f.Line); err == nil {
+		return nil
+	}
+	var location string
+	if err := json.Unmarshal(read.Line, &location); err != nil {
+		return fmt.Errorf("cannot read line of failed scenario %q: %v", f.Name, err)
+	}
+	i := strings.LastIndex(location, ":")
+	line, err := strconv.Atoi(location[i+1:])
+	if i < 0 || err != nil {
+		return fmt.Errorf("cannot read line of failed scenario %q from %q", f.Name, location)
+	}
+	if f.Feature == "" {
+		f.Feature = location[:i]
+	}
+	f.FailedStepLine = line
+	return nil
+}
+
+// The scenarios that would run in a variant, from a dry run.
+type VariantPlan struct {
+	Variant   string            `json:"variant"`
+	Tags      string            `json:"tags"`
+	Scenarios []PlannedScenario `json:"scenarios"`
+}
+
+type PlannedScenario struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Feature string   `json:"feature"`
+	Line    int      `json:"line"`
+	Example string   `json:"example,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+// How well the scenarios that ran cover the requirements of the xDS protocol.
+type Coverage struct {
+	Requirements []RequirementCoverage `json:"requirements"`
+	// Requirements with no scenarios tagged with them.
+	Uncovered []RequirementCoverage `json:"uncovered"`
+	Levels    []LevelCoverage       `json:"levels"`
+}
+
+type RequirementCoverage struct {
+	ID          string `json:"id"`
+	Level       string `json:"level"`
+	Section     string `json:"section,omitempty"`
+	Description string `json:"description,omitempty"`
+	Scenarios   int    `json:"scenarios"`
+	Passed      int    `json:"passed"`
+	Failed      int    `json:"failed"`
+}
+
+// The pass rate of the scenarios tagged with a level, like must or should.
+type LevelCoverage struct {
+	Level     string  `json:"level"`
+	Scenarios int     `json:"scenarios"`
+	Passed    int     `json:"passed"`
+	PassRate  float64 `json:"passRate"`
+}
+
+// Whether a scenario with the status fails a run. Undefined scenarios didn't check
+// what they were meant to, like one with a typo in a step, so they fail it too.
+// Pending ones don't, as steps only leave a scenario pending when the adapter
+// doesn't implement an optional call, like Restart.
+func Failing(status string) bool {
+	return status == "failed" || status == "undefined"
+}
+
+// A scenario's ID stays the same from run to run, as long as its feature file,
+// name and example row do, so results from different runs can be compared.
+func ScenarioID(feature, name, example string) string {
+	id := feature + ":" + name
+	if example != "" {
+		id = id + " " + example
+	}
+	return id
+}
+
+// Results files from before scenarios had IDs are given one when read.
+func (s ScenarioResult) ScenarioID() string {
+	if s.ID != "" {
+		return s.ID
+	}
+	return ScenarioID(s.Feature, s.Name, s.Example)
+}
+
+func (s ScenarioResult) Failure() FailedScenario {
+	return FailedScenario{
+		ID:             s.ScenarioID(),
+		Name:           s.Name,
+		Feature:        s.Feature,
+		Line:           s.Line,
+		Example:        s.Example,
+		FailedStep:     s.FailedStep,
+		FailedStepLine: s.FailedStepLine,
+		Error:          s.Error,
+	}
 }
 
 type Results struct {
-	Total            int64
-	Passed           int64
-	Failed           int64
-	Skipped          int64
-	Undefined        int64
-	Pending          int64
+	Total     int64
+	Passed    int64
+	Failed    int64
+	Skipped   int64
+	Undefined int64
+	Pending   int64
+	Steps     StepCounts
+	Coverage  *Coverage
+	// Across every variant, when any scenario timed its adapter calls.
+	PushLatency      *PushLatency
 	Variants         []string
 	ResultsByVariant []VariantResults
 }
+
+// How long something took across a number of samples, like updates reaching clients.
+type Latency struct {
+	Samples int           `json:"samples"`
+	Mean    time.Duration `json:"mean"`
+	P50     time.Duration `json:"p50"`
+	P90     time.Duration `json:"p90"`
+	P99     time.Duration `json:"p99"`
+	Max     time.Duration `json:"max"`
+}
+
+func NewLatency(samples []time.Duration) Latency {
+	latency := Latency{Samples: len(samples)}
+	if len(samples) == 0 {
+		return latency
+	}
+	sorted := append([]time.Duration{}, samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var total time.Duration
+	for _, sample := range sorted {
+		total += sample
+	}
+	percentile := func(p int) time.Duration {
+		return sorted[(len(sorted)-1)*p/100]
+	}
+	latency.Mean = total / time.Duration(len(sorted))
+	latency.P50 = percentile(50)
+	latency.P90 = percentile(90)
+	latency.P99 = percentile(99)
+	latency.Max = sorted[len(sorted)-1]
+	return latency
+}
+
+// How long adapter calls took to reach the clients, from each call being made to
+// a client receiving the resources it changed.
+type PushLatency struct {
+	Latency
+	Histogram []LatencyBucket `json:"histogram"`
+}
+
+// The number of samples up to a latency, and above the one before it. The last
+// bucket has no upper bound.
+type LatencyBucket struct {
+	UpTo  time.Duration `json:"upTo,omitempty"`
+	Count int           `json:"count"`
+}
+
+var latencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+}
+
+func NewPushLatency(samples []time.Duration) *PushLatency {
+	if len(samples) == 0 {
+		return nil
+	}
+	push := &PushLatency{Latency: NewLatency(samples), Histogram: emptyHistogram()}
+	for _, sample := range samples {
+		i := sort.Search(len(latencyBuckets), func(i int) bool { return sample <= latencyBuckets[i] })
+		push.Histogram[i].Count++
+	}
+	return push
+}
+
+func emptyHistogram() []LatencyBucket {
+	histogram := []LatencyBucket{}
+	for _, upTo := range latencyBuckets {
+		histogram = append(histogram, LatencyBucket{UpTo: upTo})
+	}
+	return append(histogram, LatencyBucket{})
+}
+
+// Adds up push latencies without their samples, like those of each variant. The
+// histogram, count, mean and max are exact, but the percentiles are the upper
+// bounds of the buckets they fall in.
+func MergePushLatency(a, b *PushLatency) *PushLatency {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	merged := &PushLatency{Histogram: emptyHistogram()}
+	merged.Samples = a.Samples + b.Samples
+	merged.Mean = (a.Mean*time.Duration(a.Samples) + b.Mean*time.Duration(b.Samples)) / time.Duration(merged.Samples)
+	merged.Max = a.Max
+	if b.Max > merged.Max {
+		merged.Max = b.Max
+	}
+	for i := range merged.Histogram {
+		merged.Histogram[i].Count = a.Histogram[i].Count + b.Histogram[i].Count
+	}
+	percentile := func(p int) time.Duration {
+		rank, seen := (merged.Samples-1)*p/100, 0
+		for _, bucket := range merged.Histogram {
+			seen += bucket.Count
+			if seen > rank && bucket.UpTo != 0 && bucket.UpTo < merged.Max {
+				return bucket.UpTo
+			}
+			if seen > rank {
+				break
+			}
+		}
+		return merged.Max
+	}
+	merged.P50 = percentile(50)
+	merged.P90 = percentile(90)
+	merged.P99 = percentile(99)
+	return merged
+}
+
+// The first few distinct errors of a long run, and how many there were in all, so
+// one error repeated through the run doesn't crowd out the rest.
+type DistinctErrors struct {
+	Limit    int
+	Count    int
+	Messages []string
+}
+
+func (d *DistinctErrors) Add(err error) {
+	d.Count++
+	message := err.Error()
+	for _, m := range d.Messages {
+		if m == message {
+			return
+		}
+	}
+	if len(d.Messages) < d.Limit {
+		d.Messages = append(d.Messages, message)
+	}
+}
+
+// Results of the load subcommand.
+type LoadResults struct {
+	Variant       string        `json:"variant"`
+	Service       string        `json:"service"`
+	Clients       int           `json:"clients"`
+	Nodes         int           `json:"nodes"`
+	Connections   int           `json:"connections"`
+	Resources     int           `json:"resources"`
+	Subscription  string        `json:"subscription"`
+	Duration      time.Duration `json:"duration"`
+	StreamsOpened int           `json:"streamsOpened"`
+	StreamErrors  int           `json:"streamErrors"`
+	Updates       int           `json:"updates"`
+	AdapterErrors int           `json:"adapterErrors"`
+	// Each update is expected to reach every client subscribed to the resource on
+	// its node.
+	ExpectedDeliveries int     `json:"expectedDeliveries"`
+	Deliveries         int     `json:"deliveries"`
+	Latency            Latency `json:"latency"`
+	// The first few distinct errors, to see what went wrong without the logs.
+	Errors       []string     `json:"errors,omitempty"`
+	ServerMemory *MemoryUsage `json:"serverMemory,omitempty"`
+}
+
+// The resident memory of the server, in bytes, sampled through a load run.
+type MemoryUsage struct {
+	Start uint64 `json:"start"`
+	Peak  uint64 `json:"peak"`
+	End   uint64 `json:"end"`
+}
+
+// Results of the model subcommand, for one variant.
+type ModelResults struct {
+	Variant  string         `json:"variant"`
+	Service  string         `json:"service"`
+	Runs     int            `json:"runs"`
+	Passed   int            `json:"passed"`
+	Failures []ModelFailure `json:"failures,omitempty"`
+}
+
+// A sequence of operations the target failed, as generated and as shrunk.
+type ModelFailure struct {
+	Seed int64    `json:"seed"`
+	Ops  []string `json:"ops"`
+	// Empty when the failure didn't happen again, so couldn't be shrunk.
+	Shrunk []string `json:"shrunk,omitempty"`
+	Flaky  bool     `json:"flaky,omitempty"`
+	Step   string   `json:"step"`
+	Error  string   `json:"error"`
+}
+
+// Results of the soak subcommand.
+type SoakResults struct {
+	Variant   string        `json:"variant"`
+	Service   string        `json:"service"`
+	Clients   int           `json:"clients"`
+	Resources int           `json:"resources"`
+	Duration  time.Duration `json:"duration"`
+	// The counts at the end of the run.
+	SoakSnapshot
+	// The first few distinct violations, to see what went wrong without the logs.
+	Violations []string `json:"violations,omitempty"`
+	// Taken every snapshot interval, to see when in the run things went wrong.
+	Snapshots []SoakSnapshot `json:"snapshots"`
+	Passed    bool           `json:"passed"`
+}
+
+// The counts of a soak run so far.
+type SoakSnapshot struct {
+	Elapsed       time.Duration `json:"elapsed"`
+	Operations    int           `json:"operations"`
+	AdapterErrors int           `json:"adapterErrors"`
+	Responses     int           `json:"responses"`
+	// Each check waits for every client to have what the adapter set.
+	Checks         int `json:"checks"`
+	FailedChecks   int `json:"failedChecks"`
+	StreamErrors   int `json:"streamErrors"`
+	ViolationCount int `json:"violationCount"`
+}
+
+// Results of the fuzz subcommand, for one variant.
+type FuzzResults struct {
+	Variant string     `json:"variant"`
+	Service string     `json:"service"`
+	Seed    int64      `json:"seed"`
+	Cases   []FuzzCase `json:"cases"`
+	Passed  bool       `json:"passed"`
+}
+
+// What the target made of a malformed exchange, and anything it got wrong.
+type FuzzCase struct {
+	Name string `json:"name"`
+	// Whether the target closed the stream, and how, or answered or ignored it.
+	Outcome  string   `json:"outcome"`
+	Problems []string `json:"problems,omitempty"`
+	Passed   bool     `json:"passed"`
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	builtin "github.com/ii/xds-test-harness/features"
+	"github.com/ii/xds-test-harness/internal/lint"
+	"github.com/ii/xds-test-harness/internal/runner"
+	"github.com/spf13/pflag"
+)
+
+// The lint subcommand checks the features without running them:
+//
+//	xds-test-harness lint [--features DIR] [--json]
+//
+// It exits with exitFailed when it finds any problems.
+func lintCommand(args []string) int {
+	flags := pflag.NewFlagSet("lint", pflag.ExitOnError)
+	override := flags.String("features", "", "directory of extra or replacement features to lint along with the built-in ones")
+	asJSON := flags.Bool("json", false, "print the problems as json instead of text")
+	_ = flags.Parse(args)
+
+	dir, err := runner.WriteFeatures(builtin.FS, *override)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitSetupError
+	}
+	defer os.RemoveAll(dir)
+
+	problems, err := lint.Lint(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitSetupError
+	}
+	if *asJSON {
+		out, _ := json.MarshalIndent(problems, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+		fmt.Printf("%v problems found\n", len(problems))
+	}
+	if len(problems) > 0 {
+		return exitFailed
+	}
+	return exitPassed
+}
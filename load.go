@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/ii/xds-test-harness/internal/load"
+	"github.com/ii/xds-test-harness/internal/report"
+	"github.com/ii/xds-test-harness/internal/types"
+	"github.com/spf13/pflag"
+)
+
+// The load subcommand opens many simulated xDS clients against the target while
+// updating their resources through the adapter, and reports how long the updates
+// took to reach them:
+//
+//	xds-test-harness load --clients 1000 --nodes 10 --duration 1m
+//
+// It exits with exitSetupError when the run couldn't be set up.
+func loadCommand(args []string) int {
+	flags := pflag.NewFlagSet("load", pflag.ExitOnError)
+	target := flags.StringP("target", "t", ":18000", "Port of xDS target to test")
+	adapter := flags.StringP("adapter", "a", ":17000", "Port of adapter on target")
+	variant := flags.String("variant", string(types.SotwNonAggregated), "xDS variant the clients use")
+	service := flags.String("service", "CDS", "Service the clients subscribe to")
+	clients := flags.Int("clients", 1000, "Number of simulated clients")
+	nodes := flags.Int("nodes", 10, "Number of node IDs the clients are spread across")
+	nodePrefix := flags.String("node-prefix", "load", "Prefix of the node IDs, which are numbered after it")
+	connections := flags.Int("connections", 10, "Number of connections to the target the client streams are spread across")
+	resources := flags.Int("resources", 10, "Number of resources set for each node")
+	subscription := flags.String("subscription", "wildcard", "What each client subscribes to: wildcard, all of the resources by name, or a number of them chosen at random")
+	duration := flags.Duration("duration", 30*time.Second, "How long to keep updating resources")
+	updateInterval := flags.Duration("update-interval", time.Second, "How often a resource is updated on every node")
+	grace := flags.Duration("grace", 2*time.Second, "How long to wait for the last updates to arrive")
+	serverPID := flags.Int("server-pid", 0, "Process ID of the target, to sample its memory through the run. Only works when the target runs on this machine.")
+	seed := flags.Int64("seed", 1, "Seed for the resources each client subscribes to")
+	asJSON := flags.Bool("json", false, "print the results as json instead of text")
+	out := flags.String("out", "load.json", "Path to write the results as json to. Set it empty to not write them.")
+	_ = flags.Parse(args)
+
+	results, err := load.Run(load.Config{
+		Target:         *target,
+		Adapter:        *adapter,
+		Variant:        types.Variant(*variant),
+		Service:        *service,
+		Clients:        *clients,
+		Nodes:          *nodes,
+		NodePrefix:     *nodePrefix,
+		Connections:    *connections,
+		Resources:      *resources,
+		Subscription:   *subscription,
+		Duration:       *duration,
+		UpdateInterval: *updateInterval,
+		Grace:          *grace,
+		ServerPID:      *serverPID,
+		Seed:           *seed,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitSetupError
+	}
+
+	data, _ := json.MarshalIndent(results, "", "  ")
+	if *out != "" {
+		if err := ioutil.WriteFile(*out, append(data, '\n'), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "cannot write load results: %v\n", err)
+			return exitSetupError
+		}
+	}
+	if *asJSON {
+		fmt.Println(string(data))
+	} else {
+		fmt.Print(report.LoadText(results))
+	}
+	return exitPassed
+}
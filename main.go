@@ -3,13 +3,21 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sync"
 	// "strings"
 
 	"github.com/cucumber/godog"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	builtin "github.com/ii/xds-test-harness/features"
+	"github.com/ii/xds-test-harness/internal/baseline"
 	"github.com/ii/xds-test-harness/internal/parser"
+	"github.com/ii/xds-test-harness/internal/report"
 	"github.com/ii/xds-test-harness/internal/runner"
+	"github.com/ii/xds-test-harness/internal/spec"
 	"github.com/ii/xds-test-harness/internal/types"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -23,8 +31,32 @@ var (
 	adapterAddress = pflag.StringP("adapter", "A", ":17000", "port of adapter on target")
 	targetAddress  = pflag.StringP("target", "T", ":18000", "port of xds target to test")
 	nodeID         = pflag.StringP("nodeID", "N", "test-id", "node id of target")
+	baselineFile   = pflag.String("baseline", "", "Path to an optional file of known failing scenarios per variant. The run only fails on failures not in it.")
+	junitReport    = pflag.String("junit", "results.xml", "Path to write a JUnit XML report of the results to. Set it empty to not write one.")
+	features       = pflag.String("features", "", "Directory of extra features to run, or replacements for the built-in ones with the same file name.")
+	requirements   = pflag.String("requirements", "", "Path to the list of xDS protocol requirements scenarios are tagged with, for the spec coverage report. Defaults to the built-in list.")
+	rerunFailed    = pflag.String("rerun-failed", "", "Path to the results.json of an earlier run. Only its failed scenarios are run again, in the variants they failed in.")
+	repeat         = pflag.Int("repeat", 1, "Run each scenario this many times, and report how often each passed, to tell flaky scenarios from failing ones.")
+	dryRun         = pflag.Bool("dry-run", false, "List the scenarios and examples that would run in each variant, without connecting to the target or adapter.")
+	asJSON         = pflag.Bool("json", false, "With --dry-run, print the scenarios as json.")
+	parallel       = pflag.Bool("parallel", false, "Run the variants side by side, each with its own node ID, like test-id-sotw-agg. Off by default, as the adapter and target have to handle several nodes at once. Scenarios tagged @exclusive still run on their own.")
+	concurrency    = pflag.Int("concurrency", 1, "How many scenarios of a variant to run at once. Above 1, each scenario runs on a node ID of its own, like test-id-3.")
+	shardFlag      = pflag.String("shard", "", "Run only part of the suite, given as i/N, like 2/4, to split it across machines. Combine the shards' results with the merge command.")
+	maxRecvMsgSize = pflag.Int("max-recv-msg-size", 0, "The largest message in bytes the Client accepts from the target. Defaults to gRPC's 4MB.")
+	htmlReport     = pflag.String("html", "results.html", "Path to write a single page html report of the results to. Set it empty to not write one.")
 	variant        = pflag.StringArrayP("variant", "V", []string{"sotw non-aggregated", "sotw aggregated", "incremental non-aggregated", "incremental aggregated"}, "xDS protocol variant your server supports. Add a separate flag per each supported variant.\n Possibleariants are: sotw non-aggregated\n, sotw aggregated\n, incremental non-aggregated\n, incremental aggregated\n.")
 	godogOpts      = godog.Options{}
+	// Where the built-in features are written out for godog to read.
+	featuresDir string
+	// Copies of them with only the scenarios to rerun.
+	selectedDirs []string
+)
+
+// Exit codes, matching godog's own.
+const (
+	exitPassed     = 0
+	exitFailed     = 1
+	exitSetupError = 2
 )
 
 func init() {
@@ -35,6 +67,24 @@ func init() {
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "diff":
+			os.Exit(diffCommand(os.Args[2:]))
+		case "lint":
+			os.Exit(lintCommand(os.Args[2:]))
+		case "merge":
+			os.Exit(mergeCommand(os.Args[2:]))
+		case "load":
+			os.Exit(loadCommand(os.Args[2:]))
+		case "model":
+			os.Exit(modelCommand(os.Args[2:]))
+		case "soak":
+			os.Exit(soakCommand(os.Args[2:]))
+		case "fuzz":
+			os.Exit(fuzzCommand(os.Args[2:]))
+		}
+	}
 	pflag.Parse()
 	godogTags := godogOpts.Tags
 
@@ -45,89 +95,334 @@ func main() {
 	// default to using CLI Flag for settings
 	supportedVariants, err := parser.ParseSupportedVariants(*variant)
 	if err != nil {
-		log.Fatal().Msgf("Cannot parse variants from CLI: %v\n", err)
+		setupError("Cannot parse variants from CLI: %v\n", err)
 	}
 	// If config present, use it for all non-debugging values
+	var nodeIdentity *core.Node
 	if *config != "" {
-		*targetAddress, *adapterAddress, *nodeID, supportedVariants = parser.ValuesFromConfig(*config)
+		*targetAddress, *adapterAddress, *nodeID, supportedVariants, err = parser.ValuesFromConfig(*config)
+		if err != nil {
+			setupError("Cannot parse config: %v\n", err)
+		}
+		nodeIdentity, err = parser.NodeFromConfig(*config)
+		if err != nil {
+			setupError("Cannot parse node from config: %v\n", err)
+		}
+	}
+	var knownFailures []baseline.Entry
+	if *baselineFile != "" {
+		knownFailures, err = baseline.Load(*baselineFile)
+		if err != nil {
+			setupError("Cannot load baseline: %v\n", err)
+		}
 	}
 
-	var results types.Results
-	for _, variant := range supportedVariants {
-		log.Info().
-			Msgf("Starting Tests for %v", string(variant))
+	featuresDir, err = runner.WriteFeatures(builtin.FS, *features)
+	if err != nil {
+		setupError("Cannot set up features: %v\n", err)
+	}
+
+	var shard *runner.Shard
+	if *shardFlag != "" {
+		parsed, err := runner.ParseShard(*shardFlag)
+		if err != nil {
+			setupError("Cannot parse --shard: %v\n", err)
+		}
+		shard = &parsed
+	}
+	if *dryRun {
+		os.Exit(planRun(supportedVariants, godogTags, shard))
+	}
+	if *concurrency < 1 {
+		setupError("--concurrency should be at least 1, got %v\n", *concurrency)
+	}
+	if *repeat < 1 {
+		setupError("--repeat should be at least 1, got %v\n", *repeat)
+	}
+	var failedBefore map[string]map[string]bool
+	if *rerunFailed != "" {
+		previous, err := readResults(*rerunFailed)
+		if err != nil {
+			setupError("Cannot read results to rerun: %v\n", err)
+		}
+		failedBefore = failedByVariant(previous)
+	}
 
+	// Suites are set up one at a time, so a bad target or adapter fails fast, but
+	// may then run side by side, each on its own node.
+	suites := []*runner.Suite{}
+	for _, variant := range supportedVariants {
+		features := featuresDir
+		var ids map[string]bool
+		if failedBefore != nil {
+			ids = failedBefore[string(variant)]
+			if len(ids) == 0 {
+				log.Info().Msgf("Nothing failed before in %v, skipping it", variant)
+				continue
+			}
+		}
+		if shard != nil {
+			ids = shardScenarios(variant, godogTags, *shard, ids)
+			log.Info().Msgf("Running %v scenarios of %v in shard %v", len(ids), variant, shard)
+		}
+		if ids != nil {
+			features, err = runner.SelectScenarios(featuresDir, ids)
+			if err != nil {
+				setupError("Cannot select scenarios to run: %v\n", err)
+			}
+			selectedDirs = append(selectedDirs, features)
+		}
 		suite := runner.NewSuite(variant, *testWriting)
-		if err = suite.StartRunner(*nodeID, *adapterAddress, *targetAddress); err != nil {
-			log.Fatal().
-				Err(err).
-				Msg("Could not start runner.")
+		suite.Node = nodeIdentity
+		suite.Features = features
+		if ids != nil {
+			suite.SelectedFrom = featuresDir
+		}
+		if !*testWriting {
+			suite.Concurrency = *concurrency
+		}
+		suite.MaxRecvMsgSize = *maxRecvMsgSize
+		suiteFeatures[suite.Name()] = features
+		suites = append(suites, suite)
+	}
+	sideBySide := *parallel && !*testWriting && len(suites) > 1
+	for _, suite := range suites {
+		node := *nodeID
+		if sideBySide {
+			suite.NodeSuffix = "-" + runner.VariantSuffix(suite.Variant)
+			node = node + suite.NodeSuffix
+		}
+		if err = suite.StartRunner(node, *adapterAddress, *targetAddress); err != nil {
+			setupError("Could not start runner: %v\n", err)
 		}
 		if err = suite.SetTags(godogTags); err != nil {
-			log.Fatal().
-				Err(err).
-				Msg("Could not set tags properly to start up test suite.")
+			setupError("Could not set tags properly to start up test suite: %v\n", err)
 		}
 		suite.ConfigureSuite()
+	}
+
+	suiteResults := make([]types.VariantResults, len(suites))
+	suiteErrors := make([]error, len(suites))
+	if sideBySide {
+		log.Info().Msgf("Running %v variants side by side", len(suites))
+		var wg sync.WaitGroup
+		for i, suite := range suites {
+			wg.Add(1)
+			go func(i int, suite *runner.Suite) {
+				defer wg.Done()
+				suiteResults[i], suiteErrors[i] = runSuite(suite)
+			}(i, suite)
+		}
+		wg.Wait()
+	} else {
+		for i, suite := range suites {
+			suiteResults[i], suiteErrors[i] = runSuite(suite)
+		}
+	}
+	var results types.Results
+	for i := range suites {
+		if suiteErrors[i] != nil {
+			setupError("Error when attempting to run test suite: %v\n", suiteErrors[i])
+		}
+		results = runner.UpdateResults(results, suiteResults[i])
+	}
+	if *requirements == "" {
+		*requirements = filepath.Join(featuresDir, "requirements.yaml")
+	}
+	addCoverage(&results, *requirements)
+	if !*testWriting {
+		if err := writeReports(results, *junitReport, *htmlReport); err != nil {
+			setupError("%v\n", err)
+		}
+	}
+
+	comparison := baseline.Compare(knownFailures, results)
+	if *baselineFile != "" {
+		printBaseline(comparison)
+	}
+	removeFeatures()
+	if len(comparison.New) > 0 {
+		os.Exit(exitFailed)
+	}
+	for _, suite := range suites {
+		if *testWriting && suite.Status != exitPassed {
+			os.Exit(exitFailed)
+		}
+	}
+	os.Exit(exitPassed)
+}
+
+// Adds how well the results cover the requirements at path, or the built-in
+// requirements when no path is given.
+func addCoverage(results *types.Results, path string) {
+	var reqs []spec.Requirement
+	var err error
+	if path == "" {
+		var f fs.File
+		if f, err = builtin.FS.Open("requirements.yaml"); err == nil {
+			defer f.Close()
+			reqs, err = spec.Parse(f)
+		}
+	} else {
+		reqs, err = spec.Load(path)
+	}
+	if err != nil {
+		log.Warn().Msgf("Not reporting spec coverage: %v\n", err)
+		return
+	}
+	results.Coverage = spec.Coverage(reqs, *results)
+}
 
+// Prints the results and writes them to results.json, and to the JUnit and html
+// reports when given paths for them.
+func writeReports(results types.Results, junitPath, htmlPath string) error {
+	fmt.Print(report.Text(results))
+	file, _ := json.MarshalIndent(results, "", "  ")
+	_ = ioutil.WriteFile("results.json", file, 0644)
+	if junitPath != "" {
+		junit, err := report.JUnit(results)
+		if err != nil {
+			return fmt.Errorf("cannot build JUnit report: %v", err)
+		}
+		if err := ioutil.WriteFile(junitPath, junit, 0644); err != nil {
+			return fmt.Errorf("cannot write JUnit report: %v", err)
+		}
+	}
+	if htmlPath != "" {
+		page, err := report.HTML(results)
+		if err != nil {
+			return fmt.Errorf("cannot build html report: %v", err)
+		}
+		if err := ioutil.WriteFile(htmlPath, page, 0644); err != nil {
+			return fmt.Errorf("cannot write html report: %v", err)
+		}
+	}
+	return nil
+}
+
+// Runs the suite as many times as asked, merging the runs into one result.
+func runSuite(suite *runner.Suite) (types.VariantResults, error) {
+	log.Info().
+		Msgf("Starting Tests for %v", string(suite.Variant))
+	runs := []types.VariantResults{}
+	for i := 0; i < *repeat; i++ {
+		if *repeat > 1 {
+			log.Info().Msgf("Run %v of %v for %v", i+1, *repeat, suite.Variant)
+		}
 		variantResults, err := suite.Run()
 		if err != nil {
-			log.Fatal().
-				Msgf("Error when attempting to run test suite: %v\n", err)
+			return variantResults, err
 		}
-		results = runner.UpdateResults(results, variantResults)
+		runs = append(runs, variantResults)
 	}
-	if !*testWriting {
-		printResults(results)
-		file, _ := json.MarshalIndent(results, "", "  ")
-		_ = ioutil.WriteFile("results.json", file, 0644)
+	if *repeat > 1 {
+		return report.MergeRepeats(runs), nil
 	}
-	os.Exit(0)
+	return runs[0], nil
 }
 
-func printResults(results types.Results) {
+// The IDs of the scenarios the shard runs in the variant, from those given or,
+// when none are, every scenario the variant would run.
+func shardScenarios(variant types.Variant, godogTags string, shard runner.Shard, only map[string]bool) map[string]bool {
+	suite := runner.NewSuite(variant, *testWriting)
+	suite.Features = featuresDir
+	if err := suite.SetTags(godogTags); err != nil {
+		setupError("Could not set tags properly to plan test suite: %v\n", err)
+	}
+	suite.ConfigureSuite()
+	plan, err := suite.Plan()
+	if err != nil {
+		setupError("Could not plan test suite: %v\n", err)
+	}
+	return shard.Select(plan, only)
+}
 
-	divider := "-------------------"
-	fmt.Println("\nTest Suite Finished\n" + divider)
-	fmt.Printf("Ran %v tests across %v variants\n\n", results.Total, len(results.Variants))
-	fmt.Println("Passed: ", results.Passed)
-	if results.Failed > 0 {
-		fmt.Println("Failed: ", results.Failed)
+func planRun(variants []types.Variant, godogTags string, shard *runner.Shard) int {
+	plans := []types.VariantPlan{}
+	for _, variant := range variants {
+		suite := runner.NewSuite(variant, *testWriting)
+		suite.Features = featuresDir
+		if err := suite.SetTags(godogTags); err != nil {
+			setupError("Could not set tags properly to plan test suite: %v\n", err)
+		}
+		suite.ConfigureSuite()
+		plan, err := suite.Plan()
+		if err != nil {
+			setupError("Could not plan test suite: %v\n", err)
+		}
+		if shard != nil {
+			selected := shard.Select(plan, nil)
+			scenarios := []types.PlannedScenario{}
+			for _, scenario := range plan.Scenarios {
+				if selected[scenario.ID] {
+					scenarios = append(scenarios, scenario)
+				}
+			}
+			plan.Scenarios = scenarios
+		}
+		plans = append(plans, plan)
 	}
-	if results.Skipped > 0 {
-		fmt.Println("Skipped: ", results.Skipped)
+	removeFeatures()
+
+	if *asJSON {
+		out, _ := json.MarshalIndent(plans, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		fmt.Print(report.PlanText(plans))
 	}
-	if results.Undefined > 0 {
-		fmt.Println("Undefined: ", results.Undefined)
+	return exitPassed
+}
+
+func setupError(format string, v ...interface{}) {
+	log.Error().Msgf(format, v...)
+	removeFeatures()
+	os.Exit(exitSetupError)
+}
+
+func removeFeatures() {
+	if featuresDir != "" {
+		os.RemoveAll(featuresDir)
 	}
-	if results.Pending > 0 {
-		fmt.Println("Pending: ", results.Pending)
+	for _, dir := range selectedDirs {
+		os.RemoveAll(dir)
 	}
-	fmt.Printf("\n\nResults broken down by Variant....\n\n")
+}
+
+// The IDs of the scenarios that failed in each variant of an earlier run,
+// counting undefined ones, as they failed the run too.
+func failedByVariant(results types.Results) map[string]map[string]bool {
+	failed := map[string]map[string]bool{}
 	for _, variant := range results.ResultsByVariant {
-		if variant.Total > 0 {
-			fmt.Println(variant.Name + "\n" + divider)
-			fmt.Println(variantResults(variant))
+		failed[variant.Name] = map[string]bool{}
+		for _, scenario := range variant.Scenarios {
+			if types.Failing(scenario.Status) {
+				failed[variant.Name][scenario.ScenarioID()] = true
+			}
 		}
 	}
+	return failed
 }
 
-func variantResults(results types.VariantResults) string {
-	total := fmt.Sprintf("Total tests: %v\n", results.Total)
-	passed := fmt.Sprintf("Passed: %v\n", results.Passed)
-	failed := fmt.Sprintf("Failed: %v\n", results.Failed)
-	skipped := fmt.Sprintf("Skipped: %v\n", results.Skipped)
-	undefined := fmt.Sprintf("Undefined: %v\n", results.Undefined)
-	pending := fmt.Sprintf("Pending: %v\n", results.Pending)
-	var failedTests string
-	if len(results.FailedScenarios) > 0 {
-		failedTests = "Failed Tests:\n"
-		for _, test := range results.FailedScenarios {
-			failedTests = failedTests +
-				"  - " + test.Name +
-				"\n    Failed Step: " + test.FailedStep +
-				"\n    Error: " + test.Error + "\n"
-		}
-	}
-	return total + passed + failed + failedTests + skipped + undefined + pending
+func printBaseline(comparison baseline.Comparison) {
+	divider := "-------------------"
+	fmt.Println("\nBaseline\n" + divider)
+	fmt.Printf("Known failures: %v\n", len(comparison.Known))
+	fmt.Printf("New failures: %v\n", len(comparison.New))
+	for _, failure := range comparison.New {
+		location := fmt.Sprintf("%v:%v", failure.Feature, failure.Line)
+		if failure.Example != "" {
+			location = location + " " + failure.Example
+		}
+		fmt.Printf("  - [%v] %v\n    At: %v\n    Error: %v\n", failure.Variant, failure.Name, location, failure.Error)
+	}
+	if len(comparison.NowPassing) > 0 {
+		fmt.Println("\nNow passing, and can be removed from the baseline:")
+		for _, entry := range comparison.NowPassing {
+			name := entry.Scenario
+			if entry.Example != "" {
+				name = name + " " + entry.Example
+			}
+			fmt.Printf("  - [%v] %v (%v)\n", entry.Variant, name, entry.Reason)
+		}
+	}
 }
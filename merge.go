@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ii/xds-test-harness/internal/baseline"
+	"github.com/ii/xds-test-harness/internal/report"
+	"github.com/ii/xds-test-harness/internal/runner"
+	"github.com/ii/xds-test-harness/internal/types"
+	"github.com/spf13/pflag"
+)
+
+// The merge subcommand combines the results of a run split with --shard, given
+// the directory each shard ran in:
+//
+//	xds-test-harness merge shard-1 shard-2 shard-3
+//
+// It writes results.json, the cucumber report of each variant, and the JUnit and
+// html reports to the current directory, as an unsharded run would. It exits as
+// an unsharded run would too, with exitFailed when any scenario failed that the
+// baseline given with --baseline doesn't list.
+func mergeCommand(args []string) int {
+	flags := pflag.NewFlagSet("merge", pflag.ExitOnError)
+	junitPath := flags.String("junit", "results.xml", "Path to write a JUnit XML report of the merged results to. Set it empty to not write one.")
+	htmlPath := flags.String("html", "results.html", "Path to write a single page html report of the merged results to. Set it empty to not write one.")
+	requirementsPath := flags.String("requirements", "", "Path to the list of xDS protocol requirements, for the spec coverage report. Defaults to the built-in list.")
+	baselinePath := flags.String("baseline", "", "Path to an optional file of known failing scenarios per variant. The merge only fails on failures not in it.")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: xds-test-harness merge [--junit FILE] [--html FILE] [--requirements FILE] [--baseline FILE] SHARD_DIR...")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitSetupError
+	}
+
+	var knownFailures []baseline.Entry
+	if *baselinePath != "" {
+		var err error
+		if knownFailures, err = baseline.Load(*baselinePath); err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot load baseline: %v\n", err)
+			return exitSetupError
+		}
+	}
+
+	shards := []types.Results{}
+	for _, dir := range flags.Args() {
+		results, err := readResults(filepath.Join(dir, "results.json"))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitSetupError
+		}
+		shards = append(shards, results)
+	}
+	results := report.MergeShards(shards)
+	addCoverage(&results, *requirementsPath)
+
+	for _, variant := range results.Variants {
+		if err := mergeCucumber(types.Variant(variant), flags.Args()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitSetupError
+		}
+	}
+	if err := writeReports(results, *junitPath, *htmlPath); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitSetupError
+	}
+	comparison := baseline.Compare(knownFailures, results)
+	if *baselinePath != "" {
+		printBaseline(comparison)
+	}
+	if len(comparison.New) > 0 {
+		return exitFailed
+	}
+	return exitPassed
+}
+
+// Merges the variant's cucumber report from every shard that has one.
+func mergeCucumber(variant types.Variant, dirs []string) error {
+	name := runner.VariantToOutputFile(variant)
+	reports := [][]byte{}
+	for _, dir := range dirs {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("cannot read cucumber report: %v", err)
+		}
+		reports = append(reports, data)
+	}
+	if len(reports) == 0 {
+		return nil
+	}
+	merged, err := report.MergeCucumber(reports)
+	if err != nil {
+		return fmt.Errorf("cannot merge %v: %v", name, err)
+	}
+	if err := ioutil.WriteFile(name, append(merged, '\n'), 0644); err != nil {
+		return fmt.Errorf("cannot write cucumber report: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/ii/xds-test-harness/internal/model"
+	"github.com/ii/xds-test-harness/internal/report"
+	"github.com/ii/xds-test-harness/internal/types"
+	"github.com/spf13/pflag"
+)
+
+// The model subcommand runs random sequences of adapter and client operations
+// against the target, checking after each that the client has what a reference
+// model says it should. Failing sequences are shrunk, and written as scenarios
+// to a feature that can be run with --features:
+//
+//	xds-test-harness model --runs 50 --length 20 --out model.feature
+//
+// It exits with exitFailed when any sequence fails, and exitSetupError when the
+// run couldn't be set up.
+func modelCommand(args []string) int {
+	flags := pflag.NewFlagSet("model", pflag.ExitOnError)
+	target := flags.StringP("target", "t", ":18000", "Port of xDS target to test")
+	adapter := flags.StringP("adapter", "a", ":17000", "Port of adapter on target")
+	variants := flags.StringArray("variant", []string{
+		string(types.SotwNonAggregated),
+		string(types.SotwAggregated),
+		string(types.IncrementalNonAggregated),
+		string(types.IncrementalAggregated),
+	}, "xDS variant to check. Can be given more than once; defaults to all of them")
+	service := flags.String("service", "CDS", "Service the sequences use")
+	runs := flags.Int("runs", 20, "Number of sequences to run for each variant")
+	length := flags.Int("length", 15, "Number of operations in each sequence")
+	seed := flags.Int64("seed", 1, "Seed of the first sequence. Each after it uses the next seed")
+	maxShrinks := flags.Int("max-shrinks", 100, "Most sequences to run while shrinking a failing one")
+	nodePrefix := flags.String("node-prefix", "model", "Prefix of the node IDs, which are numbered after it")
+	out := flags.String("out", "model.feature", "Path to write scenarios reproducing failures to. Set it empty to not write them.")
+	asJSON := flags.Bool("json", false, "print the results as json instead of text")
+	_ = flags.Parse(args)
+
+	config := model.Config{
+		Target:     *target,
+		Adapter:    *adapter,
+		Service:    *service,
+		Runs:       *runs,
+		Length:     *length,
+		Seed:       *seed,
+		NodePrefix: *nodePrefix,
+		MaxShrinks: *maxShrinks,
+	}
+	for _, variant := range *variants {
+		config.Variants = append(config.Variants, types.Variant(variant))
+	}
+	results, reproducers, err := model.Run(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitSetupError
+	}
+
+	if *out != "" && len(reproducers) > 0 {
+		if err := ioutil.WriteFile(*out, []byte(model.Feature(reproducers)), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "cannot write reproducers: %v\n", err)
+			return exitSetupError
+		}
+	}
+	if *asJSON {
+		data, _ := json.MarshalIndent(results, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		fmt.Print(report.ModelText(results))
+		if *out != "" && len(reproducers) > 0 {
+			fmt.Printf("\nReproducers written to %v\n", *out)
+		}
+	}
+	for _, result := range results {
+		if len(result.Failures) > 0 {
+			return exitFailed
+		}
+	}
+	return exitPassed
+}
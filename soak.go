@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/ii/xds-test-harness/internal/report"
+	"github.com/ii/xds-test-harness/internal/soak"
+	"github.com/ii/xds-test-harness/internal/types"
+	"github.com/spf13/pflag"
+)
+
+// The soak subcommand keeps streams open against the target for a long time,
+// churning their resources through the adapter, and checks throughout that the
+// target keeps to the protocol and its clients keep up:
+//
+//	xds-test-harness soak --duration 4h --snapshots soak-snapshots.jsonl
+//
+// It exits with exitFailed when any check or invariant failed, and
+// exitSetupError when the run couldn't be set up.
+func soakCommand(args []string) int {
+	flags := pflag.NewFlagSet("soak", pflag.ExitOnError)
+	target := flags.StringP("target", "t", ":18000", "Port of xDS target to test")
+	adapter := flags.StringP("adapter", "a", ":17000", "Port of adapter on target")
+	variant := flags.String("variant", string(types.SotwNonAggregated), "xDS variant the clients use")
+	service := flags.String("service", "CDS", "Service the clients subscribe to")
+	clients := flags.Int("clients", 5, "Number of clients, each with a stream of its own")
+	resources := flags.Int("resources", 10, "Number of resources there can be, which the churn adds, updates and removes")
+	nodeID := flags.String("node", "soak", "Node ID of the clients")
+	duration := flags.Duration("duration", time.Hour, "How long to soak the target for")
+	churnInterval := flags.Duration("churn-interval", time.Second, "How often a resource is changed through the adapter")
+	checkInterval := flags.Duration("check-interval", time.Minute, "How often to check every client has what the adapter set")
+	snapshotInterval := flags.Duration("snapshot-interval", 5*time.Minute, "How often to take a snapshot of the counts so far")
+	seed := flags.Int64("seed", 1, "Seed for the churn")
+	snapshots := flags.String("snapshots", "soak-snapshots.jsonl", "Path to append each snapshot to, as a line of json. Set it empty to not write them.")
+	asJSON := flags.Bool("json", false, "print the results as json instead of text")
+	out := flags.String("out", "soak.json", "Path to write the results as json to. Set it empty to not write them.")
+	_ = flags.Parse(args)
+
+	config := soak.Config{
+		Target:           *target,
+		Adapter:          *adapter,
+		Variant:          types.Variant(*variant),
+		Service:          *service,
+		Clients:          *clients,
+		Resources:        *resources,
+		Node:             *nodeID,
+		Duration:         *duration,
+		ChurnInterval:    *churnInterval,
+		CheckInterval:    *checkInterval,
+		SnapshotInterval: *snapshotInterval,
+		Seed:             *seed,
+	}
+	if *snapshots != "" {
+		file, err := os.OpenFile(*snapshots, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot write snapshots: %v\n", err)
+			return exitSetupError
+		}
+		defer file.Close()
+		// written as they're taken, so a run that never finishes still has them.
+		config.OnSnapshot = func(s types.SoakSnapshot) {
+			data, _ := json.Marshal(s)
+			_, _ = file.Write(append(data, '\n'))
+		}
+	}
+	results, err := soak.Run(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitSetupError
+	}
+
+	data, _ := json.MarshalIndent(results, "", "  ")
+	if *out != "" {
+		if err := ioutil.WriteFile(*out, append(data, '\n'), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "cannot write soak results: %v\n", err)
+			return exitSetupError
+		}
+	}
+	if *asJSON {
+		fmt.Println(string(data))
+	} else {
+		fmt.Print(report.SoakText(results))
+	}
+	if !results.Passed {
+		return exitFailed
+	}
+	return exitPassed
+}